@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// backup writes an archive of the configuration and data locations (e.g. for moving the service).
+func backup(cfgLocation string, dataLocation string, filename string) {
+	if len(filename) == 0 {
+		filename = fmt.Sprintf("myhome-presence-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	cfg := config.Retrieve(cfgLocation, dataLocation)
+	defer cfg.Close()
+	devices := make([]model.Device, 0, len(cfg.Devices))
+	for _, d := range cfg.Devices {
+		devices = append(devices, *d)
+	}
+
+	f, err := os.Create(filename)
+	if err == nil {
+		err = cfg.Backup(f, devices)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Fatal("Failed to backup: ", err)
+	}
+	log.Info("Saved the backup to: ", filename)
+}
+
+// restore restores an archive into the configuration and data locations (the service being stopped).
+func restore(cfgLocation string, dataLocation string, filename string) {
+	if len(filename) == 0 {
+		log.Fatal("Missing the backup file name")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	b, err := config.ReadBackup(f)
+	if err == nil {
+		err = device.CheckBackup(b)
+	}
+	if err == nil {
+		err = b.Restore(cfgLocation, dataLocation)
+	}
+	if err != nil {
+		log.Fatal("Failed to restore: ", err)
+	}
+	log.Infof("Restored %d devices from the backup made at %s (version %s)", len(b.Devices), b.Manifest.CreatedAt, b.Manifest.Version)
+}
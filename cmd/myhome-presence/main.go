@@ -9,13 +9,21 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"github.com/touchardv/myhome-presence/internal/actions/wakeonlan"
 	"github.com/touchardv/myhome-presence/internal/api"
 	"github.com/touchardv/myhome-presence/internal/config"
 	"github.com/touchardv/myhome-presence/internal/device"
 	"github.com/touchardv/myhome-presence/internal/trackers/bluetooth"
+	"github.com/touchardv/myhome-presence/internal/trackers/checkin"
+	"github.com/touchardv/myhome-presence/internal/trackers/fritzbox"
+	"github.com/touchardv/myhome-presence/internal/trackers/httpjson"
 	"github.com/touchardv/myhome-presence/internal/trackers/ipv4"
 	"github.com/touchardv/myhome-presence/internal/trackers/linksys"
+	"github.com/touchardv/myhome-presence/internal/trackers/mqtt"
+	"github.com/touchardv/myhome-presence/internal/trackers/openwrt"
+	"github.com/touchardv/myhome-presence/internal/trackers/snmp"
 	"github.com/touchardv/myhome-presence/internal/trackers/tplink"
+	"github.com/touchardv/myhome-presence/internal/trackers/unifi"
 )
 
 var (
@@ -33,17 +41,53 @@ func main() {
 	logLevel := pflag.String("log-level", log.InfoLevel.String(), "The logging level (trace, debug, info...)")
 	configLocation := pflag.String("config-location", config.DefaultCfgLocation, "The path to the directory where the configuration file is stored.")
 	dataLocation := pflag.String("data-location", config.DefaultDataLocation, "The path to the directory where the data file is stored.")
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [backup [file] | restore <file> | config validate]\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
 	pflag.Parse()
 
 	close := config.SetupLogging(*logLevel, *daemonized)
 	defer close()
 
-	log.Info("Starting...")
-	cfg := config.Retrieve(*configLocation, *dataLocation)
+	config.Version = gitVersionTag
 	bluetooth.EnableTracker()
+	checkin.EnableTracker()
+	fritzbox.EnableTracker()
+	httpjson.EnableTracker()
 	ipv4.EnableTracker()
 	linksys.EnableTracker()
+	mqtt.EnableTracker()
+	openwrt.EnableTracker()
+	snmp.EnableTracker()
 	tplink.EnableTrackers()
+	unifi.EnableTracker()
+	wakeonlan.EnableAction()
+	switch pflag.Arg(0) {
+	case "backup":
+		backup(*configLocation, *dataLocation, pflag.Arg(1))
+		return
+	case "restore":
+		restore(*configLocation, *dataLocation, pflag.Arg(1))
+		return
+	case "config":
+		if pflag.Arg(1) != "validate" {
+			pflag.Usage()
+			log.Exit(2)
+		}
+		validate(*configLocation)
+		return
+	case "":
+	default:
+		pflag.Usage()
+		log.Exit(2)
+	}
+
+	log.Info("Starting...")
+	if err := config.Validate(*configLocation); err != nil {
+		log.Fatal(err)
+	}
+	cfg := config.Retrieve(*configLocation, *dataLocation)
 	registry := device.NewRegistry(cfg)
 	server := api.NewServer(cfg.Server, registry)
 
@@ -52,8 +96,11 @@ func main() {
 	server.Start()
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	<-c
+	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	for s := <-c; s == syscall.SIGHUP; s = <-c {
+		log.Info("Reloading the configuration...")
+		server.Reload()
+	}
 
 	server.Stop()
 	stopFunc()
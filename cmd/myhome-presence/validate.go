@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+)
+
+// validate checks the configuration file, listing all its problems (e.g. before a restart or a reload).
+func validate(cfgLocation string) {
+	if err := config.Validate(cfgLocation); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		log.Exit(1)
+	}
+	fmt.Println("The configuration is valid")
+}
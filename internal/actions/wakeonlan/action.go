@@ -0,0 +1,104 @@
+package wakeonlan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableAction registers the "wake" action so that it can be executed on devices.
+func EnableAction() {
+	device.RegisterAction(name, wakeAction{})
+}
+
+const name = "wake"
+
+const defaultBroadcastAddress = "255.255.255.255:9"
+
+const (
+	// PropertyBroadcastAddress is the device property defining where the magic
+	// packets are sent (e.g. "192.168.1.255" or "192.168.1.255:7").
+	PropertyBroadcastAddress = "wol_broadcast"
+	// PropertyPassword is the device property defining the SecureOn password
+	// (6 bytes, e.g. "01:02:03:04:05:06").
+	PropertyPassword = "wol_password"
+)
+
+type wakeAction struct{}
+
+// Supports tells whether the device has an Ethernet or WiFi interface (with a MAC address).
+func (a wakeAction) Supports(d model.Device) bool {
+	return len(macAddresses(d)) > 0
+}
+
+// Execute sends a magic packet to each Ethernet or WiFi interface of the device.
+func (a wakeAction) Execute(d model.Device) error {
+	var password []byte
+	if v, found := d.Properties[PropertyPassword]; found {
+		hw, err := net.ParseMAC(v)
+		if err != nil || len(hw) != 6 {
+			return fmt.Errorf("invalid %s property value: %s", PropertyPassword, v)
+		}
+		password = hw
+	}
+	address := broadcastAddress(d.Properties[PropertyBroadcastAddress])
+
+	errs := []error{}
+	for _, mac := range macAddresses(d) {
+		if err := send(address, magicPacket(mac, password)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Debugf("[%s] Sent a magic packet to %s (%s) via %s", name, d.Identifier, mac, address)
+	}
+	return errors.Join(errs...)
+}
+
+func macAddresses(d model.Device) []net.HardwareAddr {
+	addresses := []net.HardwareAddr{}
+	for _, itf := range d.Interfaces {
+		if itf.Type != model.InterfaceEthernet && itf.Type != model.InterfaceWifi {
+			continue
+		}
+		if hw, err := net.ParseMAC(itf.MACAddress); err == nil && len(hw) == 6 {
+			addresses = append(addresses, hw)
+		}
+	}
+	return addresses
+}
+
+func broadcastAddress(v string) string {
+	if len(v) == 0 {
+		return defaultBroadcastAddress
+	}
+	if !strings.Contains(v, ":") {
+		return net.JoinHostPort(v, "9")
+	}
+	return v
+}
+
+// magicPacket builds a magic packet: 6 bytes of 0xff, the MAC address repeated
+// 16 times and the optional SecureOn password.
+func magicPacket(mac net.HardwareAddr, password []byte) []byte {
+	var b bytes.Buffer
+	b.Write(bytes.Repeat([]byte{0xff}, 6))
+	b.Write(bytes.Repeat(mac, 16))
+	b.Write(password)
+	return b.Bytes()
+}
+
+func send(address string, packet []byte) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(packet)
+	return err
+}
@@ -0,0 +1,69 @@
+package wakeonlan
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+var desktop = model.Device{
+	Identifier: "desktop",
+	Interfaces: []model.Interface{
+		{Type: model.InterfaceEthernet, MACAddress: "aa:bb:cc:dd:ee:01"},
+		{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:02"},
+	},
+}
+
+func TestSupports(t *testing.T) {
+	assert.True(t, wakeAction{}.Supports(desktop))
+	assert.False(t, wakeAction{}.Supports(model.Device{Interfaces: []model.Interface{
+		{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:02"},
+		{Type: model.InterfaceWifi, IPv4Address: "192.168.1.10"},
+	}}))
+}
+
+func TestMagicPacket(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+	packet := magicPacket(mac, nil)
+	assert.Equal(t, 102, len(packet))
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, packet[:6])
+	assert.Equal(t, []byte(mac), packet[96:])
+
+	packet = magicPacket(mac, []byte{1, 2, 3, 4, 5, 6})
+	assert.Equal(t, 108, len(packet))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, packet[102:])
+}
+
+func TestBroadcastAddress(t *testing.T) {
+	assert.Equal(t, "255.255.255.255:9", broadcastAddress(""))
+	assert.Equal(t, "192.168.1.255:9", broadcastAddress("192.168.1.255"))
+	assert.Equal(t, "192.168.1.255:7", broadcastAddress("192.168.1.255:7"))
+}
+
+func TestExecute(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	d := desktop
+	d.Properties = map[string]string{
+		PropertyBroadcastAddress: conn.LocalAddr().String(),
+		PropertyPassword:         "01:02:03:04:05:06",
+	}
+	assert.NoError(t, wakeAction{}.Execute(d))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 200)
+	n, _, err := conn.ReadFrom(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 108, n)
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+	assert.Equal(t, bytes.Repeat(mac, 16), b[6:102])
+
+	d.Properties[PropertyPassword] = "secret"
+	assert.Error(t, wakeAction{}.Execute(d))
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+)
+
+// maxBackupSize limits the size of the backups being restored.
+const maxBackupSize = 64 << 20
+
+// admin requires the admin token (as a bearer token) for accessing the admin API,
+// which is disabled when no token is configured: a backup contains the secrets,
+// and a restore replaces the configuration.
+func (c *apiContext) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.authorized(w, r) {
+			h(w, r)
+		}
+	}
+}
+
+// authorized tells whether a request has the admin token, replying with an error otherwise.
+func (c *apiContext) authorized(w http.ResponseWriter, r *http.Request) bool {
+	token := c.settings.get().AdminToken
+	if len(token) == 0 {
+		http.Error(w, "admin API disabled (no server.admin_token setting)", http.StatusForbidden)
+		return false
+	}
+	v, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(v), []byte(token)) != 1 {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="myhome-presence"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (c *apiContext) backup(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("myhome-presence-%s.tar.gz", time.Now().Format("20060102-150405"))
+	w.Header().Add("Content-Type", "application/gzip")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	if err := c.registry.Backup(w); err != nil {
+		// the response may have been partially written already
+		log.Error("Failed to backup: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (c *apiContext) restore(w http.ResponseWriter, r *http.Request) {
+	b, err := config.ReadBackup(http.MaxBytesReader(w, r.Body, maxBackupSize))
+	if err == nil {
+		err = c.registry.Restore(b)
+	}
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, config.ErrIncompatibleBackup):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, config.ErrInvalidBackup):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (c *apiContext) config(w http.ResponseWriter, r *http.Request) {
+	cfg := c.registry.Config()
+	cfg.Server = c.settings.get()
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg.Redacted())
+}
+
+func (c *apiContext) reload(w http.ResponseWriter, r *http.Request) {
+	report, err := c.applyReload()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// applyReload reloads the configuration of the registry, then the server settings
+// (the listening address only changing on restart).
+func (c *apiContext) applyReload() (device.ReloadReport, error) {
+	cfg, report, err := c.registry.Reload()
+	if err != nil {
+		log.Error("Failed to reload the configuration: ", err)
+		return report, err
+	}
+	current := c.settings.get()
+	if cfg.Server.Address != current.Address {
+		report.RestartRequired = append(report.RestartRequired, "server.address")
+		cfg.Server.Address = current.Address
+	}
+	if cfg.Server.Port != current.Port {
+		report.RestartRequired = append(report.RestartRequired, "server.port")
+		cfg.Server.Port = current.Port
+	}
+	c.settings.set(cfg.Server)
+	if len(report.RestartRequired) > 0 {
+		log.Warn("Changed settings applying on restart: ", report.RestartRequired)
+	}
+	return report, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func adminRequest(method string, url string, body io.Reader) *http.Request {
+	req, _ := http.NewRequest(method, url, body)
+	req.Header.Add("Authorization", "Bearer admin-secret")
+	return req
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	registry := device.NewRegistry(config.Config{})
+	server := NewServer(config.Server{}, registry)
+
+	// disabled by default
+	response := performRequest(server, adminRequest("GET", "/api/admin/backup", nil))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	server = NewServer(config.Server{AdminToken: "admin-secret"}, registry)
+	req, _ := http.NewRequest("GET", "/api/admin/config", nil)
+	assert.Equal(t, http.StatusUnauthorized, performRequest(server, req).Code)
+	req.Header.Add("Authorization", "Bearer wrong")
+	assert.Equal(t, http.StatusUnauthorized, performRequest(server, req).Code)
+	assert.Equal(t, http.StatusOK, performRequest(server, adminRequest("GET", "/api/admin/config", nil)).Code)
+
+	// no cross-origin requests
+	req = adminRequest("GET", "/api/admin/config", nil)
+	req.Header.Add("Origin", "http://evil.example")
+	response = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	req, _ = http.NewRequest("GET", "/api/devices", nil)
+	req.Header.Add("Origin", "http://example")
+	response = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(response, req)
+	assert.NotEmpty(t, response.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("server:\n  port: 8080\n"), 0644)
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	registry := device.NewRegistry(cfg)
+	server := NewServer(config.Server{AdminToken: "admin-secret"}, registry)
+	assert.Nil(t, registry.AddDevice(model.Device{Identifier: "phone", Status: model.StatusTracked}))
+
+	response := performRequest(server, adminRequest("GET", "/api/admin/backup", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/gzip", response.Header().Get("Content-Type"))
+	archive, _ := ioutil.ReadAll(response.Body)
+
+	assert.Nil(t, registry.RemoveDevice("phone"))
+	assert.Nil(t, registry.AddDevice(model.Device{Identifier: "tv", Status: model.StatusIgnored}))
+
+	response = performRequest(server, adminRequest("POST", "/api/admin/restore", bytes.NewBuffer(archive)))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	devices := registry.GetDevices(model.StatusUndefined)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "phone", devices[0].Identifier)
+
+	response = performRequest(server, adminRequest("POST", "/api/admin/restore", bytes.NewBufferString("not a backup")))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	os.WriteFile(filename, []byte("server:\n  hostname: foo\n  port: 8080\n  admin_token: admin-secret\n"), 0644)
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	server := NewServer(cfg.Server, device.NewRegistry(cfg))
+
+	req, _ := http.NewRequest("POST", "/api/echo", nil)
+	assert.Equal(t, http.StatusNotFound, performRequest(server, req).Code)
+
+	os.WriteFile(filename, []byte("server:\n  hostname: bar\n  port: 9090\n  admin_token: admin-secret\ntrackers:\n  echo: {}\n"), 0644)
+	response := performRequest(server, adminRequest("POST", "/api/admin/reload", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assertEqualBody(t, `{"started":["echo"],"stopped":[],"restarted":[],"mqtt_reconnected":false,"restart_required":["server.port"]}`+"\n", response)
+
+	req, _ = http.NewRequest("POST", "/api/echo", nil)
+	assert.Equal(t, http.StatusOK, performRequest(server, req).Code)
+	req, _ = http.NewRequest("GET", "/api/docs", nil)
+	body, _ := ioutil.ReadAll(performRequest(server, req).Body)
+	assert.Contains(t, string(body), "http://bar:8080/api")
+
+	os.WriteFile(filename, []byte("trackers:\n  unknown: {}\n"), 0644)
+	response = performRequest(server, adminRequest("POST", "/api/admin/reload", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mqtt-password"), []byte("secret\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`
+mqtt_server:
+  hostname: ${MQTT_HOST}
+  username: myhome
+  password: file:`+filepath.Join(dir, "mqtt-password")+`
+trackers:
+  echo:
+    token: secret
+`), 0644)
+	t.Setenv("MQTT_HOST", "broker")
+	t.Setenv("MYHOME_SERVER_PORT", "9090")
+	t.Setenv("MYHOME_SERVER_ADMIN_TOKEN", "admin-secret")
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	assert.Equal(t, "secret", cfg.MQTTServer.Password)
+	server := NewServer(cfg.Server, device.NewRegistry(cfg))
+
+	response := performRequest(server, adminRequest("GET", "/api/admin/config", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assertEqualBody(t, `{"mqtt_server":{"enabled":false,"hostname":"broker","port":0,"topic":"","username":"myhome","password":"********"},`+
+		`"server":{"address":"","hostname":"","port":9090,"ssl":false,"swagger_ui_url":"","admin_token":"********"},"storage":{"backend":""},`+
+		`"trackers":{"echo":{"token":"********"}}}`+"\n", response)
+}
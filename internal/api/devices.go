@@ -3,7 +3,9 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/touchardv/myhome-presence/internal/device"
@@ -38,9 +40,56 @@ func (c *apiContext) unregisterDevice(w http.ResponseWriter, r *http.Request) {
 func (c *apiContext) executeDeviceAction(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	q := r.URL.Query()
+	if q.Get("action") == "contact" && q.Has("wait") {
+		c.contactDevice(w, r, vars["id"], q.Get("wait"))
+		return
+	}
 	err := c.registry.ExecuteDeviceAction(vars["id"], q.Get("action"))
-	if err == nil {
+	switch {
+	case err == nil:
 		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, device.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, model.ErrInvalidDeviceAction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type contactResponse struct {
+	Seen      bool     `json:"seen"`
+	Tracker   string   `json:"tracker,omitempty"`
+	LatencyMS *float64 `json:"latency_ms,omitempty"`
+}
+
+// contactDevice contacts a device and waits for it to be seen (e.g. ?action=contact&wait=5s).
+func (c *apiContext) contactDevice(w http.ResponseWriter, r *http.Request, id string, wait string) {
+	timeout, err := time.ParseDuration(wait)
+	if err != nil || timeout <= 0 || timeout > device.MaxContactWait {
+		http.Error(w, fmt.Sprintf("invalid wait duration (expecting at most %s): %s", device.MaxContactWait, wait), http.StatusBadRequest)
+		return
+	}
+	result, err := c.registry.ContactDevice(id, timeout)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	response := contactResponse{Seen: result.Seen, Tracker: result.Tracker}
+	if result.Seen {
+		latency := float64(result.Latency.Microseconds()) / 1000
+		response.LatencyMS = &latency
+	}
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (c *apiContext) getDeviceActions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	actions, err := c.registry.DeviceActions(vars["id"])
+	if err == nil {
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(actions)
 	} else {
 		http.NotFound(w, r)
 	}
@@ -110,6 +110,69 @@ func TestUpdateDevice(t *testing.T) {
 	assert.Equal(t, "tracked", d.Status.String())
 }
 
+func TestExecuteDeviceAction(t *testing.T) {
+	devices := make(map[string]*model.Device, 0)
+	devices["foo"] = &model.Device{Identifier: "foo", Status: model.StatusDiscovered}
+	registry := device.NewRegistry(config.Config{Devices: devices})
+	server := NewServer(config.Server{}, registry)
+
+	req, _ := http.NewRequest("POST", "/api/devices/bar?action=track", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/devices/foo?action=dance", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/devices/foo?action=track", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	d, _ := registry.FindDevice("foo")
+	assert.Equal(t, model.StatusTracked, d.Status)
+}
+
+func TestContactDeviceAndWait(t *testing.T) {
+	devices := make(map[string]*model.Device, 0)
+	devices["foo"] = &model.Device{Identifier: "foo", Status: model.StatusTracked}
+	registry := device.NewRegistry(config.Config{Devices: devices})
+	server := NewServer(config.Server{}, registry)
+
+	req, _ := http.NewRequest("POST", "/api/devices/foo?action=contact&wait=forever", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/devices/foo?action=contact&wait=1m", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/devices/bar?action=contact&wait=10ms", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/devices/foo?action=contact&wait=10ms", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assertEqualBody(t, "{\"seen\":false}\n", response)
+}
+
+func TestGetDeviceActions(t *testing.T) {
+	devices := make(map[string]*model.Device, 0)
+	devices["foo"] = &model.Device{Identifier: "foo", Status: model.StatusTracked}
+	registry := device.NewRegistry(config.Config{Devices: devices})
+	server := NewServer(config.Server{}, registry)
+
+	req, _ := http.NewRequest("GET", "/api/devices/bar/actions", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("GET", "/api/devices/foo/actions", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assertEqualBody(t, "[\"contact\",\"ignore\",\"track\"]\n", response)
+}
+
 func performRequest(server *Server, req *http.Request) *httptest.ResponseRecorder {
 	response := httptest.NewRecorder()
 	server.router.ServeHTTP(response, req)
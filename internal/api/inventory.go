@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+var contentTypes = map[string]string{
+	formatCSV:  "text/csv",
+	formatJSON: "application/json",
+	formatYAML: "application/yaml",
+}
+
+// csvColumns are the fixed columns of the CSV format (one row per interface),
+// followed by one "properties.<key>" column per device property.
+var csvColumns = []string{"identifier", "description", "status", "type", "mac_address", "ipv4_address", "irk", "beacon_id"}
+
+const csvPropertyPrefix = "properties."
+
+// maxImportSize limits the size of the devices being imported.
+const maxImportSize = 8 << 20
+
+func (c *apiContext) exportDevices(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = formatJSON
+	}
+	contentType, found := contentTypes[format]
+	if !found {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+	devices := c.registry.GetDevices(model.StatusUndefined)
+	slices.SortFunc(devices, func(a, b model.Device) int { return strings.Compare(a.Identifier, b.Identifier) })
+
+	w.Header().Add("Content-Type", contentType)
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"devices.%s\"", format))
+	switch format {
+	case formatCSV:
+		writeCSV(w, devices)
+	case formatJSON:
+		json.NewEncoder(w).Encode(devices)
+	case formatYAML:
+		yaml.NewEncoder(w).Encode(devices)
+	}
+}
+
+func (c *apiContext) importDevices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mode := q.Get("mode")
+	if len(mode) == 0 {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		http.Error(w, "invalid mode: "+mode, http.StatusBadRequest)
+		return
+	}
+	// replacing removes the devices not imported, like a restore
+	if mode == "replace" && !c.authorized(w, r) {
+		return
+	}
+	format := q.Get("format")
+	if len(format) == 0 {
+		format = formatOf(r.Header.Get("Content-Type"))
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var devices []device.ImportedDevice
+	var errs []device.ImportError
+	var err error
+	switch format {
+	case formatCSV:
+		devices, errs, err = readCSV(body)
+	case formatJSON:
+		devices, errs, err = readJSON(body)
+	case formatYAML:
+		devices, errs, err = readYAML(body)
+	default:
+		err = fmt.Errorf("invalid format: %s", format)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// nothing is imported when some rows could not be read, but the other rows are still checked
+	dryRun := q.Get("dry_run") == "true"
+	report := c.registry.ImportDevices(devices, mode == "replace", dryRun || len(errs) > 0)
+	report.DryRun = dryRun
+	report.Errors = append(errs, report.Errors...)
+	slices.SortStableFunc(report.Errors, func(a, b device.ImportError) int { return a.Row - b.Row })
+
+	w.Header().Add("Content-Type", "application/json")
+	if len(report.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// formatOf returns the format matching a content type (JSON by default).
+func formatOf(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return formatYAML
+	}
+	return formatJSON
+}
+
+// readJSON reads a list of devices, the rows being their positions in the list.
+func readJSON(r io.Reader) ([]device.ImportedDevice, []device.ImportError, error) {
+	items := []json.RawMessage{}
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, nil, err
+	}
+	devices := []device.ImportedDevice{}
+	errs := []device.ImportError{}
+	for i, item := range items {
+		d := model.Device{}
+		if err := json.Unmarshal(item, &d); err != nil {
+			errs = append(errs, device.ImportError{Row: i + 1, Identifier: d.Identifier, Error: err.Error()})
+			continue
+		}
+		devices = append(devices, device.ImportedDevice{Row: i + 1, Device: d})
+	}
+	return devices, errs, nil
+}
+
+// readYAML reads a list of devices, the rows being their positions in the list.
+func readYAML(r io.Reader) ([]device.ImportedDevice, []device.ImportError, error) {
+	items := []yaml.MapSlice{}
+	if err := yaml.NewDecoder(r).Decode(&items); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+	devices := []device.ImportedDevice{}
+	errs := []device.ImportError{}
+	for i, item := range items {
+		d := model.Device{}
+		b, err := yaml.Marshal(item)
+		if err == nil {
+			err = yaml.UnmarshalStrict(b, &d)
+		}
+		if err != nil {
+			errs = append(errs, device.ImportError{Row: i + 1, Identifier: d.Identifier, Error: err.Error()})
+			continue
+		}
+		devices = append(devices, device.ImportedDevice{Row: i + 1, Device: d})
+	}
+	return devices, errs, nil
+}
+
+// readCSV reads the devices from rows of interfaces, the rows being the line numbers
+// of the first interface of the devices.
+func readCSV(r io.Reader) ([]device.ImportedDevice, []device.ImportError, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, found := columns["identifier"]; !found {
+		return nil, nil, errors.New("invalid CSV header: missing identifier column")
+	}
+
+	devices := []device.ImportedDevice{}
+	errs := []device.ImportError{}
+	rows := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			errs = append(errs, device.ImportError{Row: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, found := columns[name]; found && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		id := field("identifier")
+		d := model.Device{Identifier: id, Description: field("description"), Interfaces: []model.Interface{}}
+		if s := field("status"); len(s) > 0 {
+			if d.Status = model.StatusOf(s); d.Status == model.StatusUndefined {
+				errs = append(errs, device.ImportError{Row: line, Identifier: id, Error: model.ErrInvalidDeviceStatus.Error()})
+				continue
+			}
+		}
+		for name, i := range columns {
+			if key, found := strings.CutPrefix(name, csvPropertyPrefix); found && i < len(record) && len(record[i]) > 0 {
+				if d.Properties == nil {
+					d.Properties = make(map[string]string)
+				}
+				d.Properties[key] = record[i]
+			}
+		}
+		itf := model.Interface{
+			MACAddress:  field("mac_address"),
+			IPv4Address: field("ipv4_address"),
+			IRK:         field("irk"),
+			BeaconID:    field("beacon_id"),
+		}
+		if s := field("type"); len(s) > 0 {
+			var found bool
+			if itf.Type, found = model.InterfaceTypeOf(s); !found {
+				errs = append(errs, device.ImportError{Row: line, Identifier: id, Error: model.ErrInvalidInterfaceType.Error()})
+				continue
+			}
+		}
+		if itf != (model.Interface{Type: itf.Type}) {
+			d.Interfaces = append(d.Interfaces, itf)
+		}
+
+		// the next rows of a device only add interfaces
+		if i, found := rows[id]; found && len(id) > 0 {
+			devices[i].Device.Interfaces = append(devices[i].Device.Interfaces, d.Interfaces...)
+			continue
+		}
+		rows[id] = len(devices)
+		devices = append(devices, device.ImportedDevice{Row: line, Device: d})
+	}
+	return devices, errs, nil
+}
+
+func writeCSV(w io.Writer, devices []model.Device) {
+	keys := map[string]bool{}
+	for _, d := range devices {
+		for k := range d.Properties {
+			keys[k] = true
+		}
+	}
+	properties := slices.Sorted(maps.Keys(keys))
+	header := slices.Clone(csvColumns)
+	for _, k := range properties {
+		header = append(header, csvPropertyPrefix+k)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	for _, d := range devices {
+		interfaces := d.Interfaces
+		if len(interfaces) == 0 {
+			interfaces = []model.Interface{{}}
+		}
+		for _, itf := range interfaces {
+			record := []string{d.Identifier, d.Description, d.Status.String(), itf.Type.String(),
+				itf.MACAddress, itf.IPv4Address, itf.IRK, itf.BeaconID}
+			for _, k := range properties {
+				record = append(record, d.Properties[k])
+			}
+			writer.Write(record)
+		}
+	}
+	writer.Flush()
+}
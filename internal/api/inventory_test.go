@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func newInventoryServer() (*Server, *device.Registry) {
+	devices := map[string]*model.Device{
+		"phone": {Identifier: "phone", Description: "My phone", Status: model.StatusTracked,
+			Interfaces: []model.Interface{
+				{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"},
+				{Type: model.InterfaceBluetooth, MACAddress: "11:22:33:44:55:66"},
+			},
+			Properties: map[string]string{"owner": "me"}},
+		"tv": {Identifier: "tv", Status: model.StatusIgnored},
+	}
+	registry := device.NewRegistry(config.Config{Devices: devices})
+	return NewServer(config.Server{AdminToken: "admin-secret"}, registry), registry
+}
+
+func TestExportDevices(t *testing.T) {
+	server, registry := newInventoryServer()
+
+	req, _ := http.NewRequest("GET", "/api/inventory/export?format=csv", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "text/csv", response.Header().Get("Content-Type"))
+	assertEqualBody(t, `identifier,description,status,type,mac_address,ipv4_address,irk,beacon_id,properties.owner
+phone,My phone,tracked,wifi,aa:bb:cc:dd:ee:ff,,,,me
+phone,My phone,tracked,bluetooth,11:22:33:44:55:66,,,,me
+tv,,ignored,unknown,,,,,
+`, response)
+
+	req, _ = http.NewRequest("GET", "/api/inventory/export?format=yaml", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	b, _ := ioutil.ReadAll(response.Body)
+	assert.Contains(t, string(b), "- description: My phone\n  identifier: phone\n")
+
+	req, _ = http.NewRequest("GET", "/api/inventory/export?format=xml", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	// not shadowing the devices
+	registry.AddDevice(model.Device{Identifier: "export", Status: model.StatusTracked})
+	req, _ = http.NewRequest("GET", "/api/devices/export", nil)
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"identifier":"export"`)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{"csv", "json", "yaml"} {
+		server, registry := newInventoryServer()
+		req, _ := http.NewRequest("GET", "/api/inventory/export?format="+format, nil)
+		exported, _ := ioutil.ReadAll(performRequest(server, req).Body)
+
+		req, _ = http.NewRequest("POST", "/api/inventory/import?mode=replace&format="+format, bytes.NewBuffer(exported))
+		assert.Equal(t, http.StatusUnauthorized, performRequest(server, req).Code, format)
+		req = adminRequest("POST", "/api/inventory/import?mode=replace&format="+format, bytes.NewBuffer(exported))
+		response := performRequest(server, req)
+		assert.Equal(t, http.StatusOK, response.Code, format)
+		assertEqualBody(t, `{"dry_run":false,"added":[],"updated":["phone","tv"],"removed":[],"errors":[]}`+"\n", response)
+
+		d, err := registry.FindDevice("phone")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(d.Interfaces), format)
+		assert.Equal(t, "me", d.Properties["owner"], format)
+	}
+}
+
+func TestImportDevices(t *testing.T) {
+	server, registry := newInventoryServer()
+
+	csv := `identifier,status,type,mac_address
+laptop,tracked,wifi,00:00:00:00:00:01
+laptop,tracked,ethernet,00:00:00:00:00:02
+watch,bad,bluetooth,00:00:00:00:00:03
+tablet,tracked,wifi,AA:BB:CC:DD:EE:FF
+radio,tracked,wfii,00:00:00:00:00:04
+`
+	req, _ := http.NewRequest("POST", "/api/inventory/import", bytes.NewBufferString(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	assertEqualBody(t, `{"dry_run":false,"added":["laptop"],"updated":[],"removed":[],"errors":[`+
+		`{"row":4,"identifier":"watch","error":"invalid device status"},`+
+		`{"row":5,"identifier":"tablet","error":"address mac aa:bb:cc:dd:ee:ff already used by device phone"},`+
+		`{"row":6,"identifier":"radio","error":"invalid interface type"}]}`+"\n", response)
+	assert.Equal(t, 2, len(registry.GetDevices(model.StatusUndefined)))
+
+	req, _ = http.NewRequest("POST", "/api/inventory/import?dry_run=true", bytes.NewBufferString(`[{"identifier":"laptop","status":"tracked"}]`))
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assertEqualBody(t, `{"dry_run":true,"added":["laptop"],"updated":[],"removed":[],"errors":[]}`+"\n", response)
+	assert.Equal(t, 2, len(registry.GetDevices(model.StatusUndefined)))
+
+	req, _ = http.NewRequest("POST", "/api/inventory/import", bytes.NewBufferString(`{`))
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/inventory/import?mode=append", bytes.NewBufferString(`[]`))
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("POST", "/api/inventory/import", bytes.NewBuffer(append([]byte("["), bytes.Repeat([]byte("{},"), maxImportSize/3+1)...)))
+	response = performRequest(server, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "request body too large")
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// metrics handles a request by returning the devices metrics using the Prometheus text format.
+func (c *apiContext) metrics(w http.ResponseWriter, r *http.Request) {
+	devices := c.registry.GetDevices(model.StatusUndefined)
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Identifier < devices[j].Identifier
+	})
+
+	w.Header().Add("Content-Type", "text/plain; version=0.0.4")
+	writeMetricHeader(w, "myhome_presence_device_present", "Whether the device is present (1) or not (0).")
+	for _, d := range devices {
+		v := 0
+		if d.Present {
+			v = 1
+		}
+		fmt.Fprintf(w, "myhome_presence_device_present{identifier=\"%s\",status=\"%s\"} %d\n", escapeLabel(d.Identifier), d.Status, v)
+	}
+
+	writeMetricHeader(w, "myhome_presence_device_latency_seconds", "The latest measured round-trip time to the device.")
+	for _, d := range devices {
+		if v, ok := d.Properties[device.ReportDataLatency]; ok {
+			if latency, err := time.ParseDuration(v); err == nil {
+				fmt.Fprintf(w, "myhome_presence_device_latency_seconds{identifier=\"%s\"} %g\n", escapeLabel(d.Identifier), latency.Seconds())
+			}
+		}
+	}
+
+	writeMetricHeader(w, "myhome_presence_device_packet_loss_ratio", "The latest measured packet loss ratio (between 0 and 1).")
+	for _, d := range devices {
+		if v, ok := d.Properties[device.ReportDataPacketLoss]; ok {
+			if loss, err := strconv.ParseFloat(v, 64); err == nil {
+				fmt.Fprintf(w, "myhome_presence_device_packet_loss_ratio{identifier=\"%s\"} %g\n", escapeLabel(d.Identifier), loss)
+			}
+		}
+	}
+
+	writeMetricHeader(w, "myhome_presence_device_rssi_dbm", "The latest (smoothed) received signal strength.")
+	for _, d := range devices {
+		if v, ok := d.Properties[device.ReportDataRSSI]; ok {
+			if rssi, err := strconv.ParseFloat(v, 64); err == nil {
+				fmt.Fprintf(w, "myhome_presence_device_rssi_dbm{identifier=\"%s\"} %g\n", escapeLabel(d.Identifier), rssi)
+			}
+		}
+	}
+}
+
+func writeMetricHeader(w io.Writer, name string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
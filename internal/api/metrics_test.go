@@ -0,0 +1,40 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func TestMetrics(t *testing.T) {
+	devices := make(map[string]*model.Device, 0)
+	devices["foo"] = &model.Device{Identifier: "foo", Present: true, Status: model.StatusTracked, Properties: map[string]string{
+		device.ReportDataLatency:    "12.5ms",
+		device.ReportDataPacketLoss: "0.25",
+		device.ReportDataRSSI:       "-67",
+	}}
+	devices["bar"] = &model.Device{Identifier: "bar", Status: model.StatusDiscovered}
+	registry := device.NewRegistry(config.Config{Devices: devices})
+	server := NewServer(config.Server{}, registry)
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	bytes, _ := io.ReadAll(response.Body)
+	body := string(bytes)
+	assert.Contains(t, body, "myhome_presence_device_present{identifier=\"foo\",status=\"tracked\"} 1\n")
+	assert.Contains(t, body, "myhome_presence_device_present{identifier=\"bar\",status=\"discovered\"} 0\n")
+	assert.Contains(t, body, "myhome_presence_device_latency_seconds{identifier=\"foo\"} 0.0125\n")
+	assert.Contains(t, body, "myhome_presence_device_packet_loss_ratio{identifier=\"foo\"} 0.25\n")
+	assert.Contains(t, body, "myhome_presence_device_rssi_dbm{identifier=\"foo\"} -67\n")
+	assert.NotContains(t, body, "myhome_presence_device_latency_seconds{identifier=\"bar\"}")
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\n`, escapeLabel("a\"b\\c\n"))
+}
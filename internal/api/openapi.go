@@ -10,25 +10,38 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
 )
 
 //go:embed  openapi.yaml.tmpl
 var openapiYAML []byte
 
 func GetOpenAPISpecificationDocument(cfg config.Server) http.HandlerFunc {
+	return openAPISpecificationDocument(func() config.Server { return cfg })
+}
+
+// openAPISpecificationDocument serves the document given the current server configuration.
+func openAPISpecificationDocument(cfg func() config.Server) http.HandlerFunc {
 	t, err := template.New("openapi").Parse(string(openapiYAML))
 	if err != nil {
 		log.Fatal("Error parsing template: ", err)
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		data := make(map[string]interface{})
-		data["serverBaseURL"] = serverBaseURL(r, cfg)
+		data["serverBaseURL"] = serverBaseURL(r, cfg())
+		data["actions"] = device.ActionNames()
 		t.Execute(w, data)
 	}
 }
 
 func GetSwaggerUIHandler(cfg config.Server, path string) http.HandlerFunc {
+	return swaggerUIHandler(func() config.Server { return cfg }, path)
+}
+
+// swaggerUIHandler redirects to the Swagger UI given the current server configuration.
+func swaggerUIHandler(current func() config.Server, path string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := current()
 		scheme := ingressScheme(r, cfg)
 		hostname := ingressHostname(r, cfg)
 		port := ingressPort(r, cfg)
@@ -33,6 +33,7 @@ func TestGetOpenAPISpecificationDocument(t *testing.T) {
 	assert.NoError(t, err)
 	body := string(b)
 	assert.Contains(t, body, "https://api.server.com:443/api")
+	assert.Contains(t, body, "enum: [contact, ignore, track]")
 }
 
 func TestGetSwaggerUIHandlerWithDirectAccess(t *testing.T) {
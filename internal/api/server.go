@@ -3,7 +3,11 @@ package api
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/handlers"
@@ -15,6 +19,25 @@ import (
 
 type apiContext struct {
 	registry *device.Registry
+	settings *settings
+}
+
+// settings holds the current server configuration, which may be reloaded.
+type settings struct {
+	mutex sync.RWMutex
+	cfg   config.Server
+}
+
+func (s *settings) get() config.Server {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cfg
+}
+
+func (s *settings) set(cfg config.Server) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cfg = cfg
 }
 
 // Server is a wrapper around the router and the HTTP server.
@@ -27,7 +50,7 @@ type Server struct {
 
 // NewServer creates and initializes a new API server.
 func NewServer(cfg config.Server, r *device.Registry) *Server {
-	apiContext := apiContext{r}
+	apiContext := apiContext{registry: r, settings: &settings{cfg: cfg}}
 	router := mux.NewRouter()
 
 	cors := handlers.CORS(
@@ -36,30 +59,91 @@ func NewServer(cfg config.Server, r *device.Registry) *Server {
 		handlers.AllowedMethods([]string{"DELETE", "GET", "POST", "PUT"}),
 		handlers.AllowCredentials())
 
-	router.Handle("/", GetSwaggerUIHandler(cfg, "/api/docs")).Methods("GET")
+	router.Handle("/", swaggerUIHandler(apiContext.settings.get, "/api/docs")).Methods("GET")
 	router.HandleFunc("/health-check", healthCheck).Methods("GET")
-	router.HandleFunc("/api/docs", GetOpenAPISpecificationDocument(cfg)).Methods("GET")
+	router.HandleFunc("/metrics", apiContext.metrics).Methods("GET")
+	router.HandleFunc("/api/docs", openAPISpecificationDocument(apiContext.settings.get)).Methods("GET")
+	router.HandleFunc("/api/admin/backup", apiContext.admin(apiContext.backup)).Methods("GET")
+	router.HandleFunc("/api/admin/config", apiContext.admin(apiContext.config)).Methods("GET")
+	router.HandleFunc("/api/admin/reload", apiContext.admin(apiContext.reload)).Methods("POST")
+	router.HandleFunc("/api/admin/restore", apiContext.admin(apiContext.restore)).Methods("POST")
 	router.HandleFunc("/api/devices", apiContext.registerDevice).Methods("POST")
 	router.HandleFunc("/api/devices/{id}", apiContext.unregisterDevice).Methods("DELETE")
 	router.HandleFunc("/api/devices/{id}", apiContext.findDevice).Methods("GET")
 	router.HandleFunc("/api/devices/{id}", apiContext.executeDeviceAction).Methods("POST")
 	router.HandleFunc("/api/devices/{id}", apiContext.updateDevice).Methods("PUT")
+	router.HandleFunc("/api/devices/{id}/actions", apiContext.getDeviceActions).Methods("GET")
 	router.HandleFunc("/api/devices", apiContext.queryDevices).Methods("GET")
+	router.HandleFunc("/api/inventory/export", apiContext.exportDevices).Methods("GET")
+	router.HandleFunc("/api/inventory/import", apiContext.importDevices).Methods("POST")
+
+	// trackers receiving sightings from HTTP requests (e.g. /api/checkin), which
+	// may change on reload; the other requests still get a 404 or 405
+	router.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		_, h := apiContext.trackerHandlerOf(r.URL.Path)
+		return h != nil
+	}).HandlerFunc(apiContext.trackerHandler)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Address, cfg.Port),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
 		IdleTimeout:  60 * time.Second,
-		Handler:      cors(router),
+		Handler:      withCORS(cors, router),
 	}
 	return &Server{
-		server:  server,
-		router:  router,
-		stopped: make(chan bool, 1),
+		apiContext: apiContext,
+		server:     server,
+		router:     router,
+		stopped:    make(chan bool, 1),
 	}
 }
 
+// withCORS allows the requests of any origin, but for the admin API (not meant
+// for browsers of other origins).
+func withCORS(cors func(http.Handler) http.Handler, router http.Handler) http.Handler {
+	corsRouter := cors(router)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+			router.ServeHTTP(w, r)
+			return
+		}
+		corsRouter.ServeHTTP(w, r)
+	})
+}
+
+// trackerHandler dispatches the requests sent to /api/<tracker name>/... to the
+// (currently running) trackers receiving sightings from HTTP requests.
+func (c *apiContext) trackerHandler(w http.ResponseWriter, r *http.Request) {
+	prefix, h := c.trackerHandlerOf(r.URL.Path)
+	if h == nil {
+		// the tracker got stopped meanwhile
+		http.NotFound(w, r)
+		return
+	}
+	http.StripPrefix(prefix, h).ServeHTTP(w, r)
+}
+
+// trackerHandlerOf returns the handler of the tracker a path is sent to, if any,
+// together with the path prefix of the tracker.
+func (c *apiContext) trackerHandlerOf(path string) (string, http.Handler) {
+	handlers := c.registry.HTTPHandlers()
+	names := slices.Sorted(maps.Keys(handlers))
+	slices.Reverse(names) // e.g. "checkin/foo" before "checkin"
+	for _, name := range names {
+		prefix := "/api/" + name
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return prefix, handlers[name]
+		}
+	}
+	return "", nil
+}
+
+// Reload re-reads the configuration and applies it (e.g. on SIGHUP).
+func (s *Server) Reload() (device.ReloadReport, error) {
+	return s.apiContext.applyReload()
+}
+
 // Start runs the HTTP server (in the background).
 func (s *Server) Start() {
 	log.Info("Starting: http server")
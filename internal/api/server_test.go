@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// echoTracker is an HTTP tracker answering with the requested path.
+type echoTracker struct{}
+
+func (t *echoTracker) Loop(f device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+	<-ctx.Done()
+	return nil
+}
+
+func (t *echoTracker) Ping([]model.Device) {}
+
+func (t *echoTracker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	io.WriteString(rw, "echo:"+req.URL.Path)
+}
+
+func init() {
+	device.Register("echo", func(config.Settings) (device.Tracker, error) { return &echoTracker{}, nil }, config.Schema{})
+}
+
+func TestTrackerHandlers(t *testing.T) {
+	registry := device.NewRegistry(config.Config{
+		Devices:  map[string]*model.Device{},
+		Trackers: map[string]config.Settings{"echo": {}, "echo/second": {}},
+	})
+	server := NewServer(config.Server{}, registry)
+
+	tests := map[string]string{
+		"/api/echo":              "echo:",
+		"/api/echo/owntracks":    "echo:/owntracks",
+		"/api/echo/second":       "echo:",
+		"/api/echo/second/owntr": "echo:/owntr",
+	}
+	for path, expected := range tests {
+		req, _ := http.NewRequest("POST", path, nil)
+		response := performRequest(server, req)
+		assert.Equal(t, http.StatusOK, response.Code, path)
+		assert.Equal(t, expected, response.Body.String(), path)
+	}
+
+	req, _ := http.NewRequest("POST", "/api/echoes", nil)
+	response := performRequest(server, req)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// the other routes are left untouched
+	req, _ = http.NewRequest("PATCH", "/api/devices/foo", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, performRequest(server, req).Code)
+	req, _ = http.NewRequest("PUT", "/api/devices", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, performRequest(server, req).Code)
+}
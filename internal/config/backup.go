@@ -0,0 +1,295 @@
+package config
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Version is the version of the service, recorded in the backups.
+var Version = "undefined"
+
+// backupFormatVersion is the version of the backup archive layout; older
+// archives can be restored, newer ones cannot.
+const backupFormatVersion = 1
+
+const (
+	manifestFilename = "manifest.json"
+	configDir        = "config"
+	dataDir          = "data"
+	// maxBackupFileSize limits the size of each file of a backup being read.
+	maxBackupFileSize = 16 << 20
+)
+
+var (
+	ErrInvalidBackup      = errors.New("invalid backup")
+	ErrIncompatibleBackup = errors.New("incompatible backup")
+)
+
+// Manifest describes the content of a backup.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	Version       string    `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Files are the SHA-256 checksums of the files of the backup.
+	Files map[string]string `json:"files"`
+}
+
+// Backup is the full state of the service: the configuration files, the devices
+// and the other data files.
+type Backup struct {
+	Manifest Manifest
+	Config   Config
+	Devices  []model.Device
+	files    map[string][]byte
+}
+
+// Backup writes a (gzipped tar) archive of the configuration location, the given
+// devices and the other files of the data location.
+func (cfg *Config) Backup(w io.Writer, devices []model.Device) error {
+	files := make(map[string][]byte)
+	if err := readFiles(cfg.cfgLocation, configDir, files); err != nil {
+		return err
+	}
+	if err := readFiles(cfg.dataLocation, dataDir, files); err != nil {
+		return err
+	}
+	devices = slices.Clone(devices)
+	slices.SortFunc(devices, func(a, b model.Device) int { return strings.Compare(a.Identifier, b.Identifier) })
+	b, err := yaml.Marshal(devices)
+	if err != nil {
+		return err
+	}
+	files[path.Join(dataDir, devicesFilename)] = b
+
+	manifest := Manifest{
+		FormatVersion: backupFormatVersion,
+		Version:       Version,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+		Files:         make(map[string]string, len(files)),
+	}
+	for name, content := range files {
+		manifest.Files[name] = checksum(content)
+	}
+	b, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = writeTarFile(tw, manifestFilename, b, manifest.CreatedAt)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err == nil {
+			err = writeTarFile(tw, name, files[name], manifest.CreatedAt)
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	return err
+}
+
+// ReadBackup reads a backup archive, checking its integrity and that it can be restored.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string][]byte)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxBackupFileSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+		if len(content) > maxBackupFileSize {
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidBackup, h.Name)
+		}
+		files[h.Name] = content
+	}
+
+	b := &Backup{}
+	content, found := files[manifestFilename]
+	if !found {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, manifestFilename)
+	}
+	if err := json.Unmarshal(content, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidBackup, manifestFilename, err)
+	}
+	if b.Manifest.FormatVersion < 1 || b.Manifest.FormatVersion > backupFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d (made by version %s)",
+			ErrIncompatibleBackup, b.Manifest.FormatVersion, b.Manifest.Version)
+	}
+	delete(files, manifestFilename)
+	for name, content := range files {
+		sum, found := b.Manifest.Files[name]
+		if !found {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidBackup, name)
+		}
+		if sum != checksum(content) {
+			return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidBackup, name)
+		}
+		dir, base := path.Split(name)
+		if (dir != configDir+"/" && dir != dataDir+"/") || base == "." || base == ".." || len(base) == 0 {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidBackup, name)
+		}
+	}
+	for name := range b.Manifest.Files {
+		if _, found := files[name]; !found {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, name)
+		}
+	}
+
+	content, found = files[path.Join(configDir, cfgFilename)]
+	if !found {
+		return nil, fmt.Errorf("%w: missing %s", ErrIncompatibleBackup, cfgFilename)
+	}
+	// the references and overrides are the ones of the host running the restored configuration
+	if err := decode(content, &b.Config); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrIncompatibleBackup, cfgFilename, err)
+	}
+	if b := backend(b.Config.Storage); b != StorageYAML && b != StorageBolt {
+		return nil, fmt.Errorf("%w: invalid storage backend: %s", ErrIncompatibleBackup, b)
+	}
+	content, found = files[path.Join(dataDir, devicesFilename)]
+	if !found {
+		return nil, fmt.Errorf("%w: missing %s", ErrIncompatibleBackup, devicesFilename)
+	}
+	if err := yaml.Unmarshal(content, &b.Devices); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrIncompatibleBackup, devicesFilename, err)
+	}
+	setTimestamps(b.Devices)
+	delete(files, path.Join(dataDir, devicesFilename))
+	b.files = files
+	if b.Manifest.Version != Version {
+		log.Warnf("Restoring a backup made by version %s (running %s)", b.Manifest.Version, Version)
+	}
+	return b, nil
+}
+
+// Restore writes the configuration files, the devices and the other data files
+// of a backup (the service not running).
+func (b *Backup) Restore(cfgLocation string, dataLocation string) error {
+	if err := b.restoreFiles(cfgLocation, dataLocation); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataLocation, 0755); err != nil {
+		return err
+	}
+	store, err := openStore(b.Config.Storage.Backend, dataLocation)
+	if err != nil {
+		return err
+	}
+	err = store.Replace(b.Devices)
+	if closeErr := store.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// RestoreFiles writes the configuration files and the other data files of a
+// backup; the devices are to be replaced by the caller (the service running).
+func (cfg *Config) RestoreFiles(b *Backup) error {
+	if backend(b.Config.Storage) != backend(cfg.Storage) {
+		return fmt.Errorf("%w: the storage backend (%s) differs from the running one (%s), restore it while stopped",
+			ErrIncompatibleBackup, backend(b.Config.Storage), backend(cfg.Storage))
+	}
+	return b.restoreFiles(cfg.cfgLocation, cfg.dataLocation)
+}
+
+func (b *Backup) restoreFiles(cfgLocation string, dataLocation string) error {
+	for _, name := range slices.Sorted(maps.Keys(b.files)) {
+		dir, base := path.Split(name)
+		location := dataLocation
+		if dir == configDir+"/" {
+			location = cfgLocation
+		}
+		if err := os.MkdirAll(location, 0755); err != nil {
+			return err
+		}
+		log.Debug("Restoring: ", filepath.Join(location, base))
+		if err := writeFile(filepath.Join(location, base), b.files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFiles reads the regular files of a directory (not recursively), except
+// the ones of the devices stores: the devices are saved whatever the backend.
+func readFiles(location string, dir string, files map[string][]byte) error {
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || isStoreFile(e.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(location, e.Name()))
+		if err != nil {
+			return err
+		}
+		files[path.Join(dir, e.Name())] = content
+	}
+	return nil
+}
+
+func isStoreFile(name string) bool {
+	return name == devicesFilename || name == journalFilename(devicesFilename) ||
+		name == boltFilename || strings.HasSuffix(name, ".tmp")
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte, modTime time.Time) error {
+	h := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func backend(s Storage) string {
+	if len(s.Backend) == 0 {
+		return StorageYAML
+	}
+	return s.Backend
+}
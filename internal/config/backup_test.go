@@ -0,0 +1,119 @@
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func newBackup(t *testing.T) []byte {
+	return newBackupOf(t, "storage:\n  backend: bolt\n")
+}
+
+func newBackupOf(t *testing.T, config string) []byte {
+	cfgLocation, dataLocation := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(cfgLocation, cfgFilename), []byte(config), 0644)
+	os.WriteFile(filepath.Join(dataLocation, "state.json"), []byte("{}"), 0644)
+	cfg := Retrieve(cfgLocation, dataLocation)
+	defer cfg.Close()
+
+	var buf bytes.Buffer
+	devices := []model.Device{{Identifier: "phone", Status: model.StatusTracked}, {Identifier: "tv", Status: model.StatusIgnored}}
+	assert.Nil(t, cfg.Backup(&buf, devices))
+	return buf.Bytes()
+}
+
+func TestBackupRestore(t *testing.T) {
+	b, err := ReadBackup(bytes.NewReader(newBackup(t)))
+	assert.Nil(t, err)
+	assert.Equal(t, backupFormatVersion, b.Manifest.FormatVersion)
+	assert.Equal(t, StorageBolt, b.Config.Storage.Backend)
+	assert.Equal(t, 2, len(b.Devices))
+
+	cfgLocation, dataLocation := t.TempDir(), filepath.Join(t.TempDir(), "data")
+	assert.Nil(t, b.Restore(cfgLocation, dataLocation))
+	assert.FileExists(t, filepath.Join(dataLocation, "state.json"))
+	assert.FileExists(t, filepath.Join(dataLocation, boltFilename))
+	cfg := Retrieve(cfgLocation, dataLocation)
+	defer cfg.Close()
+	assert.Equal(t, StorageBolt, cfg.Storage.Backend)
+	assert.Equal(t, 2, len(cfg.Devices))
+}
+
+func TestRestoreOnAnotherHost(t *testing.T) {
+	t.Setenv("ROUTER_PASSWORD", "secret")
+	archive := newBackupOf(t, "storage:\n  backend: bolt\ntrackers:\n  test:\n    url: http://router\n    password: ${ROUTER_PASSWORD}\n")
+
+	// the references and overrides are resolved by the restored service, not when restoring
+	os.Unsetenv("ROUTER_PASSWORD")
+	t.Setenv("MYHOME_STORAGE_BACKEND", StorageYAML)
+	b, err := ReadBackup(bytes.NewReader(archive))
+	assert.Nil(t, err)
+	assert.Equal(t, StorageBolt, b.Config.Storage.Backend)
+	assert.Equal(t, "${ROUTER_PASSWORD}", b.Config.Trackers["test"]["password"])
+
+	cfgLocation, dataLocation := t.TempDir(), t.TempDir()
+	assert.Nil(t, b.Restore(cfgLocation, dataLocation))
+	assert.FileExists(t, filepath.Join(dataLocation, boltFilename))
+	content, err := os.ReadFile(filepath.Join(cfgLocation, cfgFilename))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "${ROUTER_PASSWORD}")
+}
+
+// rewrite rewrites the files of an archive.
+func rewrite(archive []byte, f func(name string, content []byte) []byte) []byte {
+	gz, _ := gzip.NewReader(bytes.NewReader(archive))
+	tr := tar.NewReader(gz)
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		content, _ := io.ReadAll(tr)
+		content = f(h.Name, content)
+		h.Size = int64(len(content))
+		tw.WriteHeader(h)
+		tw.Write(content)
+	}
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+func TestReadInvalidBackup(t *testing.T) {
+	archive := newBackup(t)
+
+	_, err := ReadBackup(bytes.NewReader([]byte("not a backup")))
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	tampered := rewrite(archive, func(name string, content []byte) []byte {
+		if name == "data/devices.yaml" {
+			return append(content, []byte("- identifier: radio\n")...)
+		}
+		return content
+	})
+	_, err = ReadBackup(bytes.NewReader(tampered))
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+	assert.Contains(t, err.Error(), "checksum mismatch for data/devices.yaml")
+
+	newer := rewrite(archive, func(name string, content []byte) []byte {
+		if name == manifestFilename {
+			return []byte(strings.Replace(string(content), `"format_version": 1`, `"format_version": 2`, 1))
+		}
+		return content
+	})
+	_, err = ReadBackup(bytes.NewReader(newer))
+	assert.ErrorIs(t, err, ErrIncompatibleBackup)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/touchardv/myhome-presence/pkg/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+var devicesBucket = []byte("devices")
+
+// boltStore stores the devices (as JSON documents) in a bbolt database.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func newBoltStore(filename string) (*boltStore, error) {
+	db, err := bolt.Open(filename, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(devicesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Load() ([]model.Device, error) {
+	devices := []model.Device{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).ForEach(func(k, v []byte) error {
+			d := model.Device{}
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			devices = append(devices, d)
+			return nil
+		})
+	})
+	return devices, err
+}
+
+func (s *boltStore) Put(d model.Device) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(devicesBucket), d)
+	})
+}
+
+func (s *boltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).Delete([]byte(id))
+	})
+}
+
+func (s *boltStore) Replace(devices []model.Device) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(devicesBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(devicesBucket)
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			if err := put(b, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func put(b *bolt.Bucket, d model.Device) error {
+	v, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(d.Identifier), v)
+}
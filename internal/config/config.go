@@ -4,39 +4,43 @@ import (
 	"os"
 	"path/filepath"
 
-	"gopkg.in/yaml.v2"
-
 	log "github.com/sirupsen/logrus"
 	"github.com/touchardv/myhome-presence/pkg/model"
 )
 
 // MQTT contains the MQTT server connection information.
 type MQTT struct {
-	Enabled  bool
-	Hostname string
-	Port     uint
-	Topic    string
+	Enabled  bool   `json:"enabled"`
+	Hostname string `json:"hostname"`
+	Port     uint   `json:"port"`
+	Topic    string `json:"topic"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
 }
 
 // Server contains the local web server configuration.
 type Server struct {
-	Address      string `yaml:"address"`
-	Hostname     string `yaml:"hostname"`
-	Port         uint   `yaml:"port"`
-	SSL          bool   `yaml:"ssl"`
-	SwaggerUIURL string `yaml:"swagger_ui_url"`
+	Address      string `yaml:"address" json:"address"`
+	Hostname     string `yaml:"hostname" json:"hostname"`
+	Port         uint   `yaml:"port" json:"port"`
+	SSL          bool   `yaml:"ssl" json:"ssl"`
+	SwaggerUIURL string `yaml:"swagger_ui_url" json:"swagger_ui_url"`
+	// AdminToken is the bearer token of the admin API (disabled when not set).
+	AdminToken string `yaml:"admin_token" json:"admin_token"`
 }
 
 type Settings map[string]string
 
 // Config contains the list of all devices to be tracked.
 type Config struct {
-	Devices      map[string]*model.Device `yaml:"-"`
-	MQTTServer   MQTT                     `yaml:"mqtt_server"`
-	Server       Server                   `yaml:"server"`
-	Trackers     map[string]Settings      `yaml:"trackers"`
+	Devices      map[string]*model.Device `yaml:"-" json:"-"`
+	MQTTServer   MQTT                     `yaml:"mqtt_server" json:"mqtt_server"`
+	Server       Server                   `yaml:"server" json:"server"`
+	Storage      Storage                  `yaml:"storage" json:"storage"`
+	Trackers     map[string]Settings      `yaml:"trackers" json:"trackers"`
 	cfgLocation  string                   `yaml:"-"`
 	dataLocation string                   `yaml:"-"`
+	store        Store                    `yaml:"-"`
 }
 
 // DefaultCfgLocation corresponds to the default path to the directory where
@@ -51,35 +55,92 @@ const DefaultDataLocation = "/var/lib/myhome"
 
 const devicesFilename = "devices.yaml"
 
-// Retrieve reads and parses the configuration file.
+// Retrieve reads and parses the configuration file, then loads the devices.
 func Retrieve(cfgLocation string, dataLocation string) Config {
 	cfg := Config{
 		cfgLocation:  cfgLocation,
 		dataLocation: dataLocation,
 	}
 	cfg.loadConfig(cfgLocation, cfgFilename)
-	cfg.loadDevicesData(dataLocation, devicesFilename)
+	store, err := openStore(cfg.Storage.Backend, dataLocation)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.store = store
+	cfg.loadDevicesData(store)
 	return cfg
 }
 
+// Reload validates, then re-reads the configuration file; the devices and their store are kept.
+func (cfg *Config) Reload() (Config, error) {
+	if err := Validate(cfg.cfgLocation); err != nil {
+		return *cfg, err
+	}
+	reloaded := Config{
+		Devices:      cfg.Devices,
+		cfgLocation:  cfg.cfgLocation,
+		dataLocation: cfg.dataLocation,
+		store:        cfg.store,
+	}
+	err := reloaded.readConfig(cfg.cfgLocation, cfgFilename)
+	return reloaded, err
+}
+
+// redacted replaces the value of a secret setting when shown.
+const redacted = "********"
+
+// Redacted returns a copy of the settings whose secrets (like the passwords) are
+// redacted, e.g. for being shown.
+func (cfg *Config) Redacted() Config {
+	c := Config{
+		MQTTServer: cfg.MQTTServer,
+		Server:     cfg.Server,
+		Storage:    cfg.Storage,
+		Trackers:   make(map[string]Settings, len(cfg.Trackers)),
+	}
+	if len(c.MQTTServer.Password) > 0 {
+		c.MQTTServer.Password = redacted
+	}
+	if len(c.Server.AdminToken) > 0 {
+		c.Server.AdminToken = redacted
+	}
+	for name, settings := range cfg.Trackers {
+		schema, _ := SchemaOf(name)
+		c.Trackers[name] = make(Settings, len(settings))
+		for k, v := range settings {
+			// the unknown settings may be secrets too
+			if s, found := schema.Lookup(k); !found || s.Secret {
+				v = redacted
+			}
+			c.Trackers[name][k] = v
+		}
+	}
+	return c
+}
+
 func (cfg *Config) loadConfig(location string, name string) {
+	if err := cfg.readConfig(location, name); err != nil {
+		log.Fatal(err)
+	}
+	cfg.Devices = make(map[string]*model.Device)
+}
+
+func (cfg *Config) readConfig(location string, name string) error {
 	filename := filepath.Join(location, name)
 	log.Debug("Loading config from: ", filename)
 	content, err := os.ReadFile(filename)
 	if err == nil {
-		err = yaml.Unmarshal(content, &cfg)
-	}
-	if err != nil {
-		log.Fatal(err)
+		err = resolve(filename, content, cfg)
 	}
-	cfg.Devices = make(map[string]*model.Device)
+	return err
 }
 
-func (cfg *Config) loadDevicesData(location string, name string) {
-	devices, err := load(location, name)
+func (cfg *Config) loadDevicesData(store Store) {
+	devices, err := store.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
+	setTimestamps(devices)
 	for _, d := range devices {
 		if _, ok := cfg.Devices[d.Identifier]; !ok {
 			cfg.Devices[d.Identifier] = &model.Device{}
@@ -89,7 +150,39 @@ func (cfg *Config) loadDevicesData(location string, name string) {
 	log.Infof("Loaded %d devices", len(cfg.Devices))
 }
 
-// Save persists the device list to disk.
+// Save persists (replaces) the whole device list.
 func (cfg *Config) Save(devices []model.Device) {
-	save(devices, cfg.dataLocation, devicesFilename)
+	if cfg.store == nil {
+		return
+	}
+	if err := cfg.store.Replace(devices); err != nil {
+		log.Error("Failed to save the devices: ", err)
+	}
+}
+
+// Put persists a (new or updated) device.
+func (cfg *Config) Put(d model.Device) {
+	if cfg.store == nil {
+		return
+	}
+	if err := cfg.store.Put(d); err != nil {
+		log.Errorf("Failed to save the device %s: %s", d.Identifier, err)
+	}
+}
+
+// Delete removes a persisted device given its identifier.
+func (cfg *Config) Delete(id string) {
+	if cfg.store == nil {
+		return
+	}
+	if err := cfg.store.Delete(id); err != nil {
+		log.Errorf("Failed to delete the device %s: %s", id, err)
+	}
+}
+
+// Close releases the resources held by the devices store.
+func (cfg *Config) Close() {
+	if cfg.store != nil {
+		cfg.store.Close()
+	}
 }
@@ -21,7 +21,7 @@ func TestLoadingDevicesState(t *testing.T) {
 	cwd, _ := os.Getwd()
 	cfg := Config{cfgLocation: cwd, dataLocation: cwd}
 	cfg.loadConfig(cwd, "config.yaml.example")
-	cfg.loadDevicesData(cwd, "devices.yaml.example")
+	cfg.loadDevicesData(newYAMLStore(filepath.Join(cwd, "devices.yaml.example")))
 	assert.Equal(t, 2, len(cfg.Devices))
 
 	device := cfg.Devices["my-smartwatch"]
@@ -50,7 +50,7 @@ func TestSavingDevicesState(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	devices := []model.Device{{Identifier: "foobar", Present: true}}
-	err = save(devices, tempDir, "test-devices.yaml")
+	err = save(devices, filepath.Join(tempDir, "test-devices.yaml"))
 	assert.Nil(t, err)
 	assert.FileExists(t, filepath.Join(tempDir, "test-devices.yaml"))
 }
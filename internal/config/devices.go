@@ -1,8 +1,13 @@
 package config
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -10,8 +15,132 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-func load(location string, name string) ([]model.Device, error) {
-	filename := filepath.Join(location, name)
+// yamlStore stores the devices in a YAML file (the snapshot), and the changes
+// made since in a journal; the journal is compacted into the snapshot on save.
+type yamlStore struct {
+	filename   string
+	journal    *journal
+	maxEntries int
+	mutex      sync.Mutex
+	devices    map[string]model.Device
+}
+
+func newYAMLStore(filename string) *yamlStore {
+	return &yamlStore{
+		filename:   filename,
+		journal:    newJournal(journalFilename(filename)),
+		maxEntries: defaultMaxJournalEntries,
+	}
+}
+
+func journalFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".journal"
+}
+
+func (s *yamlStore) Load() ([]model.Device, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.devices = nil
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	return s.sorted(), nil
+}
+
+func (s *yamlStore) Put(d model.Device) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	s.devices[d.Identifier] = d
+	return s.record(journalEntry{Op: journalPut, Device: &d})
+}
+
+func (s *yamlStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if _, found := s.devices[id]; !found {
+		return nil
+	}
+	delete(s.devices, id)
+	return s.record(journalEntry{Op: journalDelete, ID: id})
+}
+
+func (s *yamlStore) Replace(devices []model.Device) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.devices = make(map[string]model.Device, len(devices))
+	for _, d := range devices {
+		s.devices[d.Identifier] = d
+	}
+	return s.compact()
+}
+
+func (s *yamlStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.journal.close()
+}
+
+// ensureLoaded loads the snapshot, then replays the journal.
+func (s *yamlStore) ensureLoaded() error {
+	if s.devices != nil {
+		return nil
+	}
+	devices, err := load(s.filename)
+	if err != nil {
+		return err
+	}
+	s.devices = make(map[string]model.Device, len(devices))
+	for _, d := range devices {
+		s.devices[d.Identifier] = d
+	}
+	return s.journal.replay(func(e journalEntry) {
+		switch {
+		case e.Op == journalPut && e.Device != nil:
+			s.devices[e.Device.Identifier] = *e.Device
+		case e.Op == journalDelete:
+			delete(s.devices, e.ID)
+		}
+	})
+}
+
+func (s *yamlStore) record(e journalEntry) error {
+	if err := s.journal.append(e); err != nil {
+		return err
+	}
+	if s.journal.entries >= s.maxEntries {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact writes the snapshot, then empties the journal.
+func (s *yamlStore) compact() error {
+	if err := save(s.sorted(), s.filename); err != nil {
+		return err
+	}
+	return s.journal.truncate()
+}
+
+func (s *yamlStore) sorted() []model.Device {
+	devices := slices.Collect(maps.Values(s.devices))
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Identifier < devices[j].Identifier
+	})
+	return devices
+}
+
+func load(filename string) ([]model.Device, error) {
 	_, err := os.Stat(filename)
 	if os.IsNotExist(err) {
 		return []model.Device{}, nil
@@ -22,8 +151,53 @@ func load(location string, name string) ([]model.Device, error) {
 	if err == nil {
 		err = yaml.Unmarshal(content, &devices)
 	}
+	return devices, err
+}
+
+func save(devices []model.Device, filename string) error {
+	bytes, err := yaml.Marshal(devices)
+	if err != nil {
+		return err
+	}
+	log.Debug("Saving devices to: ", filename)
+	return writeFile(filename, bytes)
+}
+
+// writeFile writes a file atomically (and durably) by renaming a temporary file.
+func writeFile(filename string, data []byte) error {
+	tmpFile := filename + ".tmp"
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, filename)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(filename))
+	}
+	return err
+}
 
-	// "data migration" for setting a created_at/updated_at values
+// syncDir makes a rename in a directory durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// setTimestamps is a "data migration" for setting the created_at/updated_at values.
+func setTimestamps(devices []model.Device) {
 	now := time.Now()
 	for i := range devices {
 		if devices[i].CreatedAt.IsZero() {
@@ -33,26 +207,4 @@ func load(location string, name string) ([]model.Device, error) {
 			devices[i].UpdatedAt = now
 		}
 	}
-	return devices, err
-}
-
-func save(devices []model.Device, location string, name string) error {
-	trackedDevices := make([]model.Device, 0, len(devices))
-	for _, d := range devices {
-		if d.Status == model.StatusTracked ||
-			d.Status == model.StatusIgnored {
-			trackedDevices = append(trackedDevices, d)
-		}
-	}
-	bytes, err := yaml.Marshal(trackedDevices)
-	if err == nil {
-		filename := filepath.Join(location, name)
-		log.Debug("Saving devices to: ", filename)
-		tmpFile := filename + ".tmp"
-		err = os.WriteFile(tmpFile, bytes, 0644)
-		if err == nil {
-			err = os.Rename(tmpFile, filename)
-		}
-	}
-	return err
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+)
+
+// defaultMaxJournalEntries is the number of journal entries after which the
+// journal is compacted into the snapshot.
+const defaultMaxJournalEntries = 500
+
+// journalEntry is a registry mutation: a device was added/updated, or deleted.
+type journalEntry struct {
+	Op     string        `json:"op"`
+	Device *model.Device `json:"device,omitempty"`
+	ID     string        `json:"id,omitempty"`
+}
+
+// journal is an append-only (JSON lines) file of the changes made since the last snapshot.
+type journal struct {
+	filename string
+	file     *os.File
+	entries  int
+}
+
+func newJournal(filename string) *journal {
+	return &journal{filename: filename}
+}
+
+// replay applies the entries of the journal, dropping a truncated last
+// entry (e.g. following a power cut).
+func (j *journal) replay(apply func(journalEntry)) error {
+	content, err := os.ReadFile(j.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if n := len(content); n > 0 && content[n-1] != '\n' {
+		// drop the truncated entry, so that the next ones can be appended
+		content = content[:bytes.LastIndexByte(content, '\n')+1]
+		if err := os.Truncate(j.filename, int64(len(content))); err != nil {
+			return err
+		}
+	}
+	j.entries = 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		e := journalEntry{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			log.Warnf("Ignored an invalid entry of %s: %s", j.filename, err)
+			continue
+		}
+		apply(e)
+		j.entries++
+	}
+	if j.entries > 0 {
+		log.Infof("Replayed %d change(s) from: %s", j.entries, j.filename)
+	}
+	return scanner.Err()
+}
+
+// append writes (and syncs) an entry.
+func (j *journal) append(e journalEntry) error {
+	if j.file == nil {
+		f, err := os.OpenFile(j.filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		j.file = f
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.entries++
+	return j.file.Sync()
+}
+
+// truncate empties the journal, once its entries are part of a snapshot.
+func (j *journal) truncate() error {
+	j.close()
+	j.entries = 0
+	err := os.Remove(j.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of the environment variables overriding the settings
+// (besides the trackers ones), e.g. MYHOME_MQTT_SERVER_PASSWORD.
+const envPrefix = "MYHOME_"
+
+// filePrefix is the prefix of the values read from a file (e.g. a Docker secret).
+const filePrefix = "file:"
+
+// literalPrefix is the prefix of the values kept as is (e.g. a password starting with "file:").
+const literalPrefix = "literal:"
+
+// envReference matches a reference to an environment variable, or an escaped
+// one (like "$${NAME}", kept as "${NAME}").
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// parse reads a configuration file, applying the environment overrides, then
+// expanding the references of its values (a value can reference environment
+// variables, like "${NAME}", or a file, like "file:/run/secrets/name", unless
+// escaped, like "$${NAME}" or "literal:file:name").
+func (v *validator) parse(content []byte) *yaml.Node {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		v.problems = append(v.problems, Problem{File: v.filename, Message: err.Error()})
+		return nil
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	v.override(doc.Content[0], "", reflect.TypeOf(Config{}))
+	v.expand("", doc.Content[0])
+	return doc
+}
+
+// resolve reads a configuration file into the configuration, resolving the
+// environment overrides and the references of its values.
+func resolve(filename string, content []byte, cfg *Config) error {
+	v := newValidator(filename)
+	doc := v.parse(content)
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+	return decodeNode(doc, cfg)
+}
+
+// decode reads a configuration file into the configuration as is (e.g. the one
+// of a backup, made on another host).
+func decode(content []byte, cfg *Config) error {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	return decodeNode(doc, cfg)
+}
+
+// decodeNode decodes a (resolved) node like yaml.v2 does, so that the YAML 1.1
+// booleans (like yes/no, on/off) are still accepted; yaml.v3 only provides the
+// line numbers of the values.
+func decodeNode(n *yaml.Node, out interface{}) error {
+	content, err := yaml.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return yamlv2.Unmarshal(content, out)
+}
+
+// override replaces the settings of a mapping for which an environment variable
+// is defined (like MYHOME_<SECTION>_<SETTING>).
+func (v *validator) override(n *yaml.Node, key string, t reflect.Type) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for name, ft := range fields(t) {
+		qualified := strings.TrimPrefix(key+"."+name, ".")
+		switch ft.Kind() {
+		case reflect.Struct:
+			child := lookup(n, name)
+			if child == nil {
+				section := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				v.override(section, qualified, ft)
+				if len(section.Content) > 0 {
+					n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, section)
+				}
+				continue
+			}
+			if isNull(child) {
+				*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: child.Line, Column: child.Column}
+			}
+			v.override(child, qualified, ft)
+
+		case reflect.Map, reflect.Slice:
+
+		default:
+			env := envName(qualified)
+			value, found := os.LookupEnv(env)
+			if !found {
+				continue
+			}
+			child := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			if previous := lookup(n, name); previous != nil {
+				*previous = *child
+				child = previous
+			} else {
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+			}
+			v.overrides[child] = env
+		}
+	}
+}
+
+// expand resolves the references of the values of a node.
+func (v *validator) expand(key string, n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.expand(strings.TrimPrefix(key+"."+n.Content[i].Value, "."), n.Content[i+1])
+		}
+
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			v.expand(key, item)
+		}
+
+	case yaml.ScalarNode:
+		value, err := expand(n.Value)
+		if err != nil {
+			v.report(n, "%s: %s", key, err)
+			return
+		}
+		if value != n.Value {
+			// the type of the value is resolved again (e.g. a port number)
+			n.Value, n.Tag, n.Style = value, "", 0
+		}
+	}
+}
+
+// expand resolves the environment variables referenced by a value, then reads
+// the file it references, if any.
+func expand(value string) (string, error) {
+	if v, found := strings.CutPrefix(value, literalPrefix); found {
+		return v, nil
+	}
+	var err error
+	value = envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		name := envReference.FindStringSubmatch(ref)[1]
+		v, found := os.LookupEnv(name)
+		if !found && err == nil {
+			err = fmt.Errorf("undefined environment variable: %s", name)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	if filename, found := strings.CutPrefix(value, filePrefix); found {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	return value, nil
+}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// lookup returns the value of a key of a mapping, if any.
+func lookup(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// fields returns the types of the (YAML) fields of a configuration struct, by name.
+func fields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			// the default naming of the YAML decoder
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
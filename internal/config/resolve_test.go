@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("ROUTER_HOST", "192.168.1.1")
+	t.Setenv("SECRETS", t.TempDir())
+	os.WriteFile(filepath.Join(os.Getenv("SECRETS"), "password"), []byte("secret\n"), 0644)
+
+	v, err := expand("http://${ROUTER_HOST}/api")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://192.168.1.1/api", v)
+
+	v, err = expand("file:${SECRETS}/password")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", v)
+
+	v, err = expand("$ROUTER_HOST")
+	assert.NoError(t, err)
+	assert.Equal(t, "$ROUTER_HOST", v)
+
+	v, err = expand("$${ROUTER_HOST}:${ROUTER_HOST}")
+	assert.NoError(t, err)
+	assert.Equal(t, "${ROUTER_HOST}:192.168.1.1", v)
+
+	v, err = expand("literal:file:${SECRETS}/password")
+	assert.NoError(t, err)
+	assert.Equal(t, "file:${SECRETS}/password", v)
+
+	_, err = expand("${UNDEFINED_HOST}")
+	assert.EqualError(t, err, "undefined environment variable: UNDEFINED_HOST")
+
+	_, err = expand("file:/does/not/exist")
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("MQTT_PASSWORD", "secret")
+	t.Setenv("TOKEN", "abc")
+	t.Setenv("MYHOME_SERVER_PORT", "9090")
+	t.Setenv("MYHOME_MQTT_SERVER_USERNAME", "myhome")
+	t.Setenv("MYHOME_STORAGE_BACKEND", "bolt")
+
+	cfg := Config{}
+	assert.NoError(t, resolve("config.yaml", []byte(`
+mqtt_server:
+  hostname: broker
+  port: 1883
+  password: ${MQTT_PASSWORD}
+server:
+  port: 8080
+trackers:
+  test:
+    url: http://foo
+    token.alice: ${TOKEN}
+`), &cfg))
+	assert.Equal(t, MQTT{Hostname: "broker", Port: 1883, Username: "myhome", Password: "secret"}, cfg.MQTTServer)
+	assert.Equal(t, uint(9090), cfg.Server.Port)
+	assert.Equal(t, StorageBolt, cfg.Storage.Backend)
+	assert.Equal(t, Settings{"url": "http://foo", "token.alice": "abc"}, cfg.Trackers["test"])
+
+	cfg = Config{}
+	assert.NoError(t, resolve("config.yaml", []byte(`
+mqtt_server:
+  enabled: yes
+server:
+  ssl: on
+trackers:
+  test:
+    url: "yes"
+`), &cfg))
+	assert.True(t, cfg.MQTTServer.Enabled)
+	assert.True(t, cfg.Server.SSL)
+	assert.Equal(t, Settings{"url": "yes"}, cfg.Trackers["test"])
+	assert.Empty(t, validate("config.yaml", []byte("server:\n  ssl: on\n")))
+
+	cfg = Config{}
+	assert.NoError(t, resolve("config.yaml", []byte{}, &cfg))
+	assert.Equal(t, uint(9090), cfg.Server.Port)
+}
+
+func TestDecode(t *testing.T) {
+	t.Setenv("MYHOME_SERVER_PORT", "9090")
+
+	cfg := Config{}
+	assert.NoError(t, decode([]byte(`
+server:
+  port: 8080
+trackers:
+  test:
+    url: ${UNDEFINED_URL}
+    password: file:/does/not/exist
+`), &cfg))
+	assert.Equal(t, uint(8080), cfg.Server.Port)
+	assert.Equal(t, Settings{"url": "${UNDEFINED_URL}", "password": "file:/does/not/exist"}, cfg.Trackers["test"])
+
+	assert.NoError(t, decode([]byte{}, &cfg))
+	assert.Error(t, decode([]byte("server: ["), &cfg))
+}
+
+func TestValidateResolved(t *testing.T) {
+	t.Setenv("MYHOME_SERVER_PORT", "eighty")
+
+	assert.Equal(t, Problems{
+		{File: "config.yaml", Line: 0, Message: "server.port: invalid uint value: eighty (from MYHOME_SERVER_PORT)"},
+		{File: "config.yaml", Line: 4, Message: "trackers.test.url: undefined environment variable: UNDEFINED_URL"},
+		{File: "config.yaml", Line: 5, Message: "trackers.test.count: invalid int value: many"},
+	}, validate("config.yaml", []byte(`
+trackers:
+  test:
+    url: ${UNDEFINED_URL}
+    count: many
+`)))
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		MQTTServer: MQTT{Hostname: "broker", Username: "myhome", Password: "secret"},
+		Trackers: map[string]Settings{
+			"test":    {"url": "http://foo", "token.alice": "abc"},
+			"unknown": {"password": "secret"},
+		},
+	}
+	redactedCfg := cfg.Redacted()
+	assert.Equal(t, MQTT{Hostname: "broker", Username: "myhome", Password: redacted}, redactedCfg.MQTTServer)
+	assert.Equal(t, Settings{"url": "http://foo", "token.alice": redacted}, redactedCfg.Trackers["test"])
+	assert.Equal(t, Settings{"password": redacted}, redactedCfg.Trackers["unknown"])
+	assert.Equal(t, "secret", cfg.MQTTServer.Password)
+	assert.Equal(t, "abc", cfg.Trackers["test"]["token.alice"])
+
+	assert.EqualError(t, Setting{Type: TypeInt, Secret: true}.Check("secret"), "invalid int value")
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingType is the type of the value of a tracker setting.
+type SettingType string
+
+const (
+	TypeString   SettingType = "string"
+	TypeInt      SettingType = "int"
+	TypeFloat    SettingType = "float"
+	TypeBool     SettingType = "bool"
+	TypeDuration SettingType = "duration"
+)
+
+// Setting describes a tracker setting.
+type Setting struct {
+	// Name is the setting key, where "*" matches any part of a key (e.g. "token.*").
+	Name     string
+	Type     SettingType
+	Required bool
+	// Default is the value used when the setting is missing.
+	Default string
+	// Secret tells that the value must not be shown (e.g. a password).
+	Secret      bool
+	Description string
+}
+
+// Schema describes the settings of a tracker.
+type Schema []Setting
+
+type trackerSpec struct {
+	schema Schema
+	check  func(Settings) error
+}
+
+var trackers = make(map[string]trackerSpec)
+
+// RegisterSchema records the settings schema of a tracker, together with a function
+// checking its settings further (e.g. by instantiating the tracker).
+func RegisterSchema(tracker string, schema Schema, check func(Settings) error) {
+	trackers[tracker] = trackerSpec{schema: schema, check: check}
+}
+
+// SchemaOf returns the settings schema of a tracker given its configuration name
+// (like "<tracker>/<instance>").
+func SchemaOf(name string) (Schema, bool) {
+	tracker, _, _ := strings.Cut(name, "/")
+	spec, found := trackers[tracker]
+	return spec.schema, found
+}
+
+// Lookup returns the description of a setting given its key.
+func (s Schema) Lookup(key string) (Setting, bool) {
+	for _, setting := range s {
+		if matched, _ := path.Match(setting.Name, key); matched {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Check checks that a value is valid given the setting type (a secret value not being shown).
+func (s Setting) Check(value string) error {
+	var err error
+	switch s.Type {
+	case TypeInt:
+		_, err = strconv.Atoi(value)
+	case TypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeDuration:
+		_, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		if s.Secret {
+			return fmt.Errorf("invalid %s value", s.Type)
+		}
+		return fmt.Errorf("invalid %s value: %s", s.Type, value)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Missing returns an error naming the first required setting that is missing
+// (at least one setting must match a required setting name like "token.*").
+func (s Schema) Missing(cfg Settings) error {
+	for _, setting := range s {
+		if !setting.Required {
+			continue
+		}
+		found := false
+		for key := range cfg {
+			if matched, _ := path.Match(setting.Name, key); matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("missing '%s' setting", setting.Name)
+		}
+	}
+	return nil
+}
+
+// Value returns the value of a setting, or else its default value.
+func (s Schema) Value(cfg Settings, key string) string {
+	if v, found := cfg[key]; found {
+		return v
+	}
+	setting, _ := s.Lookup(key)
+	return setting.Default
+}
+
+// Bool returns the value of a boolean setting, or else its default value.
+func (s Schema) Bool(cfg Settings, key string) (bool, error) {
+	v, err := s.value(cfg, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s setting value: %s", key, v)
+	}
+	return b, nil
+}
+
+// Duration returns the value of a (strictly positive) duration setting, or else
+// its default value.
+func (s Schema) Duration(cfg Settings, key string) (time.Duration, error) {
+	v, err := s.value(cfg, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s setting value: %s", key, v)
+	}
+	return d, nil
+}
+
+// Float returns the value of a float setting, or else its default value.
+func (s Schema) Float(cfg Settings, key string) (float64, error) {
+	v, err := s.value(cfg, key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s setting value: %s", key, v)
+	}
+	return f, nil
+}
+
+// List returns the items of a comma separated setting value, or else of its default value.
+func (s Schema) List(cfg Settings, key string) []string {
+	return SplitList(s.Value(cfg, key))
+}
+
+// value returns the value of a setting, or else its default value, if any.
+func (s Schema) value(cfg Settings, key string) (string, error) {
+	v := s.Value(cfg, key)
+	if len(v) == 0 {
+		return "", fmt.Errorf("missing '%s' setting", key)
+	}
+	return v, nil
+}
+
+// SplitList returns the (trimmed, non empty) items of a comma separated list.
+func SplitList(v string) []string {
+	items := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			items = append(items, s)
+		}
+	}
+	return items
+}
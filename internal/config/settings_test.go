@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaSettings(t *testing.T) {
+	schema := Schema{
+		{Name: "url", Type: TypeString, Required: true},
+		{Name: "token.*", Type: TypeString, Required: true},
+		{Name: "interval", Type: TypeDuration, Default: "1m"},
+		{Name: "enabled", Type: TypeBool, Default: "true"},
+		{Name: "radius", Type: TypeFloat},
+		{Name: "values", Type: TypeString, Default: "a, b"},
+	}
+
+	assert.EqualError(t, schema.Missing(Settings{"token.alice": "secret"}), "missing 'url' setting")
+	assert.EqualError(t, schema.Missing(Settings{"url": "http://foo"}), "missing 'token.*' setting")
+	assert.NoError(t, schema.Missing(Settings{"url": "http://foo", "token.alice": "secret"}))
+
+	cfg := Settings{"interval": "30s", "enabled": "false"}
+	d, err := schema.Duration(cfg, "interval")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, d)
+	d, err = schema.Duration(Settings{}, "interval")
+	assert.NoError(t, err)
+	assert.Equal(t, 1*time.Minute, d)
+	_, err = schema.Duration(Settings{"interval": "0s"}, "interval")
+	assert.EqualError(t, err, "invalid interval setting value: 0s")
+
+	b, err := schema.Bool(cfg, "enabled")
+	assert.NoError(t, err)
+	assert.False(t, b)
+	b, err = schema.Bool(Settings{}, "enabled")
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = schema.Float(Settings{}, "radius")
+	assert.EqualError(t, err, "missing 'radius' setting")
+	_, err = schema.Float(Settings{"radius": "far"}, "radius")
+	assert.EqualError(t, err, "invalid radius setting value: far")
+
+	assert.Equal(t, []string{"a", "b"}, schema.List(Settings{}, "values"))
+	assert.Equal(t, []string{"c"}, schema.List(Settings{"values": " c,,"}, "values"))
+	assert.Equal(t, "http://foo", schema.Value(Settings{"url": "http://foo"}, "url"))
+	assert.Empty(t, schema.Value(Settings{}, "url"))
+}
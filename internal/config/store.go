@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// Store persists the devices.
+type Store interface {
+	// Load returns all the persisted devices.
+	Load() ([]model.Device, error)
+
+	// Put adds or replaces a device.
+	Put(model.Device) error
+
+	// Delete removes a device given its identifier.
+	Delete(id string) error
+
+	// Replace replaces all the persisted devices.
+	Replace([]model.Device) error
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+const (
+	// StorageYAML stores the devices in a YAML file (rewritten on every change).
+	StorageYAML = "yaml"
+	// StorageBolt stores the devices in an embedded transactional key/value database.
+	StorageBolt = "bolt"
+)
+
+const boltFilename = "devices.db"
+
+// Storage contains the devices storage configuration.
+type Storage struct {
+	Backend string `yaml:"backend" json:"backend"`
+}
+
+// migratedSuffix is appended to the files of a store once migrated (so that
+// they are not migrated again, e.g. when switching back to their backend).
+const migratedSuffix = ".migrated"
+
+// openStore opens the configured store in the given location, migrating
+// the devices persisted by another backend when the store is empty.
+func openStore(backend string, location string) (Store, error) {
+	backends := map[string]string{
+		StorageYAML: filepath.Join(location, devicesFilename),
+		StorageBolt: filepath.Join(location, boltFilename),
+	}
+	if len(backend) == 0 {
+		backend = StorageYAML
+	}
+	filename, found := backends[backend]
+	if !found {
+		return nil, fmt.Errorf("invalid storage backend: %s", backend)
+	}
+	empty := !exists(backend, filename)
+	store, err := newStore(backend, filename)
+	if err != nil {
+		return store, err
+	}
+
+	for other, otherFilename := range backends {
+		if other == backend || !exists(other, otherFilename) {
+			continue
+		}
+		if !empty {
+			log.Warnf("Ignoring the devices of %s, using the ones of %s", otherFilename, filename)
+			continue
+		}
+		if err := migrate(other, otherFilename, store); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to migrate from %s: %w", otherFilename, err)
+		}
+		if err := archive(other, otherFilename); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to archive %s: %w", otherFilename, err)
+		}
+		log.Infof("Migrated the devices from %s to %s", otherFilename, filename)
+		empty = false
+	}
+	return store, nil
+}
+
+func exists(backend string, filename string) bool {
+	if _, err := os.Stat(filename); err == nil {
+		return true
+	}
+	if backend == StorageYAML {
+		_, err := os.Stat(journalFilename(filename))
+		return err == nil
+	}
+	return false
+}
+
+// archive renames the files of a migrated store.
+func archive(backend string, filename string) error {
+	filenames := []string{filename}
+	if backend == StorageYAML {
+		filenames = append(filenames, journalFilename(filename))
+	}
+	for _, f := range filenames {
+		if err := os.Rename(f, f+migratedSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStore(backend string, filename string) (Store, error) {
+	if backend == StorageBolt {
+		return newBoltStore(filename)
+	}
+	return newYAMLStore(filename), nil
+}
+
+func migrate(backend string, filename string, to Store) error {
+	from, err := newStore(backend, filename)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+	devices, err := from.Load()
+	if err != nil {
+		return err
+	}
+	return to.Replace(devices)
+}
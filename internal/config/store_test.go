@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func testStore(t *testing.T, store Store) {
+	devices, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(devices))
+
+	seenAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	phone := model.Device{
+		Identifier: "phone",
+		Interfaces: []model.Interface{{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:ff", IRK: "ec0234a357c8ad05341010a60a397d9b"}},
+		LastSeenAt: seenAt,
+		Properties: map[string]string{"min_rssi": "-80"},
+		Status:     model.StatusTracked,
+	}
+	assert.Nil(t, store.Put(phone))
+	assert.Nil(t, store.Put(model.Device{Identifier: "tv", Status: model.StatusDiscovered}))
+	phone.Present = true
+	assert.Nil(t, store.Put(phone))
+	assert.Nil(t, store.Delete("tv"))
+	assert.Nil(t, store.Delete("radio"))
+
+	devices, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.True(t, devices[0].Present)
+	assert.Equal(t, seenAt, devices[0].LastSeenAt.UTC())
+	assert.Equal(t, phone.Interfaces, devices[0].Interfaces)
+	assert.Equal(t, "-80", devices[0].Properties["min_rssi"])
+	assert.Equal(t, model.StatusTracked, devices[0].Status)
+
+	assert.Nil(t, store.Replace([]model.Device{{Identifier: "tv", Status: model.StatusIgnored}}))
+	devices, _ = store.Load()
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "tv", devices[0].Identifier)
+	assert.Nil(t, store.Close())
+}
+
+func TestYAMLStore(t *testing.T) {
+	testStore(t, newYAMLStore(filepath.Join(t.TempDir(), devicesFilename)))
+}
+
+func TestYAMLStoreJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), devicesFilename)
+	store := newYAMLStore(filename)
+	store.maxEntries = 3
+	assert.Nil(t, store.Replace([]model.Device{{Identifier: "phone", Status: model.StatusTracked}}))
+	assert.NoFileExists(t, journalFilename(filename))
+
+	// the changes are appended to the journal (and not to the snapshot)...
+	assert.Nil(t, store.Put(model.Device{Identifier: "tv", Status: model.StatusTracked}))
+	assert.Nil(t, store.Delete("phone"))
+	assert.FileExists(t, journalFilename(filename))
+	snapshot, _ := load(filename)
+	assert.Equal(t, 1, len(snapshot))
+	assert.Equal(t, "phone", snapshot[0].Identifier)
+
+	// ...and replayed when loading (e.g. after a crash)
+	devices, err := newYAMLStore(filename).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "tv", devices[0].Identifier)
+
+	// the journal gets compacted into the snapshot when too long
+	assert.Nil(t, store.Put(model.Device{Identifier: "radio", Status: model.StatusTracked}))
+	assert.NoFileExists(t, journalFilename(filename))
+	snapshot, _ = load(filename)
+	assert.Equal(t, 2, len(snapshot))
+	assert.Nil(t, store.Close())
+}
+
+func TestYAMLStoreTruncatedJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), devicesFilename)
+	os.WriteFile(journalFilename(filename), []byte(`{"op":"put","device":{"identifier":"tv","status":"tracked"}}
+{"op":"put","device":{"identifier":"pho`), 0644)
+
+	store := newYAMLStore(filename)
+	devices, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "tv", devices[0].Identifier)
+
+	// the next changes are not lost
+	assert.Nil(t, store.Put(model.Device{Identifier: "phone", Status: model.StatusTracked}))
+	store.Close()
+	devices, _ = newYAMLStore(filename).Load()
+	assert.Equal(t, 2, len(devices))
+}
+
+func TestBoltStore(t *testing.T) {
+	store, err := newBoltStore(filepath.Join(t.TempDir(), boltFilename))
+	assert.Nil(t, err)
+	testStore(t, store)
+}
+
+func TestOpenStore(t *testing.T) {
+	dir := t.TempDir()
+	_, err := openStore("sql", dir)
+	assert.EqualError(t, err, "invalid storage backend: sql")
+
+	store, err := openStore("", dir)
+	assert.Nil(t, err)
+	assert.IsType(t, &yamlStore{}, store)
+	assert.Nil(t, store.Put(model.Device{Identifier: "phone", Status: model.StatusTracked}))
+	store.Close()
+
+	// the devices are migrated to the new backend
+	store, err = openStore(StorageBolt, dir)
+	assert.Nil(t, err)
+	assert.IsType(t, &boltStore{}, store)
+	devices, _ := store.Load()
+	assert.Equal(t, 1, len(devices))
+	assert.Nil(t, store.Put(model.Device{Identifier: "tv", Status: model.StatusTracked}))
+	store.Close()
+
+	// ...but only once (the migrated store is archived)
+	assert.NoFileExists(t, filepath.Join(dir, devicesFilename))
+	assert.FileExists(t, journalFilename(filepath.Join(dir, devicesFilename))+migratedSuffix)
+	store, _ = openStore(StorageBolt, dir)
+	devices, _ = store.Load()
+	assert.Equal(t, 2, len(devices))
+	store.Close()
+
+	// and back, keeping the devices added meanwhile
+	store, _ = openStore(StorageYAML, dir)
+	devices, _ = store.Load()
+	assert.Equal(t, 2, len(devices))
+	assert.Nil(t, store.Put(model.Device{Identifier: "radio", Status: model.StatusTracked}))
+	store.Close()
+	assert.NoFileExists(t, filepath.Join(dir, boltFilename))
+
+	// and forth again
+	store, _ = openStore(StorageBolt, dir)
+	devices, _ = store.Load()
+	assert.Equal(t, 3, len(devices))
+	store.Close()
+
+	// when both stores exist, the configured one wins
+	assert.NoError(t, newYAMLStore(filepath.Join(dir, devicesFilename)).Replace(nil))
+	store, _ = openStore(StorageBolt, dir)
+	devices, _ = store.Load()
+	assert.Equal(t, 3, len(devices))
+	store.Close()
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an invalid configuration setting, located in the configuration file.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// Problems lists all the problems of a configuration file.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+// enums are the allowed values of some settings (besides none).
+var enums = map[string][]string{
+	"storage.backend": {StorageYAML, StorageBolt},
+}
+
+// Validate checks the configuration file (unknown or invalid settings, unknown
+// trackers, missing tracker settings...), reporting all its problems at once.
+func Validate(cfgLocation string) error {
+	filename := filepath.Join(cfgLocation, cfgFilename)
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if problems := validate(filename, content); len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+type validator struct {
+	filename string
+	problems Problems
+	// overrides are the values set by environment variables (and their names)
+	overrides map[*yaml.Node]string
+}
+
+func newValidator(filename string) *validator {
+	return &validator{filename: filename, overrides: make(map[*yaml.Node]string)}
+}
+
+func validate(filename string, content []byte) Problems {
+	v := newValidator(filename)
+	doc := v.parse(content)
+	if doc == nil {
+		return v.problems
+	}
+	v.checkStruct("", doc.Content[0], reflect.TypeOf(Config{}))
+	slices.SortStableFunc(v.problems, func(a, b Problem) int { return a.Line - b.Line })
+	return v.problems
+}
+
+func (v *validator) report(n *yaml.Node, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if env, found := v.overrides[n]; found {
+		message += fmt.Sprintf(" (from %s)", env)
+	}
+	v.problems = append(v.problems, Problem{File: v.filename, Line: n.Line, Message: message})
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// checkStruct checks a mapping against the (YAML) fields of a configuration struct.
+func (v *validator) checkStruct(key string, n *yaml.Node, t reflect.Type) {
+	if isNull(n) {
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		if len(key) == 0 {
+			v.report(n, "expecting a mapping")
+		} else {
+			v.report(n, "%s: expecting a mapping", key)
+		}
+		return
+	}
+	fields := fields(t)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, value := n.Content[i], n.Content[i+1]
+		qualified := strings.TrimPrefix(key+"."+k.Value, ".")
+		t, found := fields[k.Value]
+		if !found {
+			v.report(k, "unknown setting: %s", qualified)
+			continue
+		}
+		v.checkValue(qualified, value, t)
+	}
+}
+
+func (v *validator) checkValue(key string, n *yaml.Node, t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Struct:
+		v.checkStruct(key, n, t)
+
+	case reflect.Map:
+		if key == "trackers" {
+			v.checkTrackers(n)
+		}
+
+	default:
+		if isNull(n) {
+			return
+		}
+		if n.Kind != yaml.ScalarNode {
+			v.report(n, "%s: expecting a value", key)
+			return
+		}
+		if err := decodeNode(n, reflect.New(t).Interface()); err != nil {
+			v.report(n, "%s: invalid %s value: %s", key, t.Kind(), n.Value)
+			return
+		}
+		if values, found := enums[key]; found && !slices.Contains(values, n.Value) {
+			v.report(n, "%s: invalid value: %s (expecting one of %s)", key, n.Value, strings.Join(values, ", "))
+		}
+	}
+}
+
+func (v *validator) checkTrackers(n *yaml.Node) {
+	if isNull(n) {
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		v.report(n, "trackers: expecting a mapping")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, value := n.Content[i], n.Content[i+1]
+		name := k.Value
+		tracker, _, _ := strings.Cut(name, "/")
+		spec, found := trackers[tracker]
+		if !found {
+			v.report(k, "unknown tracker: %s", name)
+			continue
+		}
+		if !isNull(value) && value.Kind != yaml.MappingNode {
+			v.report(value, "trackers.%s: expecting a mapping", name)
+			continue
+		}
+
+		count := len(v.problems)
+		settings := make(Settings)
+		matched := make(map[string]bool)
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			sk, sv := value.Content[j], value.Content[j+1]
+			key := fmt.Sprintf("trackers.%s.%s", name, sk.Value)
+			setting, found := spec.schema.Lookup(sk.Value)
+			switch {
+			case !found:
+				v.report(sk, "unknown setting: %s", key)
+			case sv.Kind != yaml.ScalarNode:
+				v.report(sv, "%s: expecting a value", key)
+			default:
+				if err := setting.Check(sv.Value); err != nil {
+					v.report(sv, "%s: %s", key, err)
+				}
+				settings[sk.Value] = sv.Value
+				matched[setting.Name] = true
+			}
+		}
+		for _, setting := range spec.schema {
+			if setting.Required && !matched[setting.Name] {
+				v.report(k, "trackers.%s: missing setting: %s", name, setting.Name)
+			}
+		}
+		if len(v.problems) == count && spec.check != nil {
+			if err := spec.check(settings); err != nil {
+				v.report(k, "trackers.%s: %s", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	RegisterSchema("test", Schema{
+		{Name: "url", Type: TypeString, Required: true},
+		{Name: "interval", Type: TypeDuration, Default: "1m"},
+		{Name: "count", Type: TypeInt},
+		{Name: "token.*", Type: TypeString, Secret: true},
+	}, func(s Settings) error {
+		if s["url"] == "invalid" {
+			return errors.New("invalid url setting value: invalid")
+		}
+		return nil
+	})
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, validate("config.yaml", []byte(`
+server:
+  port: 8080
+storage:
+  backend: bolt
+trackers:
+  test:
+    url: http://foo
+    interval: 30s
+    token.alice: secret
+  test/other:
+    url: http://bar
+`)))
+
+	problems := validate("config.yaml", []byte(`
+server:
+  port: eighty
+  adress: 0.0.0.0
+storage:
+  backend: sql
+trackers:
+  test:
+    interval: soon
+    colour: red
+  unknown: {}
+  test/other:
+    url: invalid
+`))
+	assert.Equal(t, Problems{
+		{File: "config.yaml", Line: 3, Message: "server.port: invalid uint value: eighty"},
+		{File: "config.yaml", Line: 4, Message: "unknown setting: server.adress"},
+		{File: "config.yaml", Line: 6, Message: "storage.backend: invalid value: sql (expecting one of yaml, bolt)"},
+		{File: "config.yaml", Line: 8, Message: "trackers.test: missing setting: url"},
+		{File: "config.yaml", Line: 9, Message: "trackers.test.interval: invalid duration value: soon"},
+		{File: "config.yaml", Line: 10, Message: "unknown setting: trackers.test.colour"},
+		{File: "config.yaml", Line: 11, Message: "unknown tracker: unknown"},
+		{File: "config.yaml", Line: 12, Message: "trackers.test/other: invalid url setting value: invalid"},
+	}, problems)
+	assert.Contains(t, problems.Error(), "config.yaml:3: server.port: invalid uint value: eighty\n")
+}
+
+func TestValidateFile(t *testing.T) {
+	location := t.TempDir()
+	assert.Error(t, Validate(location))
+
+	os.WriteFile(filepath.Join(location, cfgFilename), []byte("server: [\n"), 0644)
+	var problems Problems
+	assert.ErrorAs(t, Validate(location), &problems)
+	assert.Equal(t, 1, len(problems))
+
+	os.WriteFile(filepath.Join(location, cfgFilename), []byte("trackers:\n  test:\n    url: http://foo\n"), 0644)
+	assert.NoError(t, Validate(location))
+}
+
+func TestSchemaLookup(t *testing.T) {
+	schema, found := SchemaOf("test/other")
+	assert.True(t, found)
+	s, found := schema.Lookup("token.bob")
+	assert.True(t, found)
+	assert.True(t, s.Secret)
+	_, found = schema.Lookup("tokens")
+	assert.False(t, found)
+
+	assert.NoError(t, Setting{Type: TypeInt}.Check("42"))
+	assert.EqualError(t, Setting{Type: TypeBool}.Check("maybe"), "invalid bool value: maybe")
+}
@@ -0,0 +1,64 @@
+package device
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// Action is an action that can be executed on a device (e.g. "wake").
+type Action interface {
+	// Supports tells whether the action can be executed on the given device.
+	Supports(model.Device) bool
+
+	// Execute executes the action on the given device.
+	Execute(model.Device) error
+}
+
+// ActionFunc is an Action (supported by all devices) defined by a function.
+type ActionFunc func(model.Device) error
+
+func (f ActionFunc) Supports(model.Device) bool {
+	return true
+}
+
+func (f ActionFunc) Execute(d model.Device) error {
+	return f(d)
+}
+
+// builtinActions are the actions provided by the registry itself.
+var builtinActions = []string{"contact", "ignore", "track"}
+
+var actions map[string]Action = make(map[string]Action)
+
+// RegisterAction records an Action by name.
+func RegisterAction(name string, a Action) {
+	actions[name] = a
+}
+
+// ActionNames returns the names of all the (built-in and registered) actions.
+func ActionNames() []string {
+	names := slices.Concat(builtinActions, slices.Collect(maps.Keys(actions)))
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// newActions returns the actions available to a registry: the built-in ones
+// and the registered ones.
+func (r *Registry) newActions() map[string]Action {
+	a := map[string]Action{
+		"contact": ActionFunc(func(d model.Device) error {
+			r.watchdog.ping([]model.Device{d})
+			return nil
+		}),
+		"ignore": ActionFunc(func(d model.Device) error {
+			return r.updateStatus(d.Identifier, model.StatusIgnored)
+		}),
+		"track": ActionFunc(func(d model.Device) error {
+			return r.updateStatus(d.Identifier, model.StatusTracked)
+		}),
+	}
+	maps.Copy(a, actions)
+	return a
+}
@@ -0,0 +1,69 @@
+package device
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+type dummyAction struct {
+	executed []string
+}
+
+func (a *dummyAction) Supports(d model.Device) bool {
+	return d.Status == model.StatusTracked
+}
+
+func (a *dummyAction) Execute(d model.Device) error {
+	a.executed = append(a.executed, d.Identifier)
+	if d.Identifier == "broken" {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestRegisterAction(t *testing.T) {
+	a := &dummyAction{}
+	RegisterAction("dummy", a)
+	defer delete(actions, "dummy")
+	assert.Equal(t, []string{"contact", "dummy", "ignore", "track"}, ActionNames())
+
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"foo":    {Identifier: "foo", Status: model.StatusTracked},
+		"bar":    {Identifier: "bar", Status: model.StatusIgnored},
+		"broken": {Identifier: "broken", Status: model.StatusTracked},
+	}})
+
+	names, err := registry.DeviceActions("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"contact", "dummy", "ignore", "track"}, names)
+	names, _ = registry.DeviceActions("bar")
+	assert.Equal(t, []string{"contact", "ignore", "track"}, names)
+	_, err = registry.DeviceActions("baz")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, registry.ExecuteDeviceAction("foo", "dummy"))
+	assert.Equal(t, model.ErrInvalidDeviceAction, registry.ExecuteDeviceAction("bar", "dummy"))
+	assert.EqualError(t, registry.ExecuteDeviceAction("broken", "dummy"), "failed")
+	assert.Equal(t, []string{"foo", "broken"}, a.executed)
+}
+
+func TestExecuteBuiltinActions(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"foo": {Identifier: "foo", Status: model.StatusDiscovered},
+	}})
+
+	assert.Nil(t, registry.ExecuteDeviceAction("foo", "ignore"))
+	d, _ := registry.FindDevice("foo")
+	assert.Equal(t, model.StatusIgnored, d.Status)
+
+	assert.Nil(t, registry.ExecuteDeviceAction("foo", "track"))
+	d, _ = registry.FindDevice("foo")
+	assert.Equal(t, model.StatusTracked, d.Status)
+
+	assert.Equal(t, ErrNotFound, registry.ExecuteDeviceAction("bar", "track"))
+	assert.Equal(t, model.ErrInvalidDeviceAction, registry.ExecuteDeviceAction("foo", "dance"))
+}
@@ -0,0 +1,77 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// Backup writes an archive of the configuration, the devices and the other data files.
+func (r *Registry) Backup(w io.Writer) error {
+	r.mutex.RLock()
+	cfg := r.cfg
+	devices := make([]model.Device, 0, len(r.devices))
+	for _, d := range r.devices {
+		devices = append(devices, snapshot(d))
+	}
+	r.mutex.RUnlock()
+	return cfg.Backup(w, devices)
+}
+
+// CheckBackup checks the devices of a backup (like imported ones) before restoring it.
+func CheckBackup(b *config.Backup) error {
+	devices := make([]ImportedDevice, 0, len(b.Devices))
+	for i, d := range b.Devices {
+		devices = append(devices, ImportedDevice{Row: i + 1, Device: d})
+	}
+	_, errors := checkDevices(devices, make(map[string]string))
+	if len(errors) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errors))
+	for _, e := range errors {
+		messages = append(messages, fmt.Sprintf("device %d (%s): %s", e.Row, e.Identifier, e.Error))
+	}
+	return fmt.Errorf("%w: %s", config.ErrInvalidBackup, strings.Join(messages, ", "))
+}
+
+// Restore restores the configuration and data files of a backup, then replaces
+// all the devices; the configuration applies on restart. Nothing is restored
+// when the devices of the backup are invalid.
+func (r *Registry) Restore(b *config.Backup) error {
+	if err := CheckBackup(b); err != nil {
+		return err
+	}
+	r.mutex.RLock()
+	cfg := r.cfg
+	r.mutex.RUnlock()
+	if err := cfg.RestoreFiles(b); err != nil {
+		return err
+	}
+
+	r.persisting.Lock()
+	defer r.persisting.Unlock()
+	r.mutex.Lock()
+	for id, d := range r.devices {
+		delete(r.devices, id)
+		r.onRemoved(d)
+	}
+	devices := make([]model.Device, 0, len(b.Devices))
+	for _, d := range b.Devices {
+		r.devices[d.Identifier] = &d
+		devices = append(devices, snapshot(&d))
+		r.onAdded(&d)
+	}
+	clear(r.resolved)
+	clear(r.changed)
+	clear(r.departed)
+	r.mutex.Unlock()
+
+	cfg.Save(devices)
+	log.Infof("Restored %d devices from a backup made at %s", len(b.Devices), b.Manifest.CreatedAt)
+	return nil
+}
@@ -0,0 +1,39 @@
+package device
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func TestRestoreInvalidDevices(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	os.WriteFile(filename, []byte("server:\n  port: 8080\n"), 0644)
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	registry := NewRegistry(cfg)
+	assert.Nil(t, registry.AddDevice(model.Device{Identifier: "phone", Status: model.StatusTracked}))
+
+	archive := bytes.Buffer{}
+	assert.NoError(t, registry.Backup(&archive))
+	b, err := config.ReadBackup(&archive)
+	assert.NoError(t, err)
+	assert.False(t, b.Devices[0].UpdatedAt.IsZero())
+
+	os.WriteFile(filename, []byte("server:\n  port: 9090\n"), 0644)
+	b.Devices = append(b.Devices, model.Device{Identifier: "phone", Status: model.StatusTracked}, model.Device{Identifier: "tv"})
+	err = registry.Restore(b)
+	assert.ErrorIs(t, err, config.ErrInvalidBackup)
+	assert.ErrorContains(t, err, "device 2 (phone): duplicate device identifier, device 3 (tv): missing device status")
+
+	// nothing was restored
+	content, _ := os.ReadFile(filename)
+	assert.Equal(t, "server:\n  port: 9090\n", string(content))
+	assert.Equal(t, 1, len(registry.GetDevices(model.StatusUndefined)))
+}
@@ -0,0 +1,75 @@
+package device
+
+import (
+	"slices"
+	"time"
+
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// MaxContactWait is the longest time one can wait for a device to answer being contacted.
+const MaxContactWait = 10 * time.Second
+
+// ContactResult is the outcome of contacting a device.
+type ContactResult struct {
+	// Seen tells whether the device was seen before the timeout.
+	Seen bool
+	// Tracker is the name of the tracker having seen the device.
+	Tracker string
+	// Latency is the round-trip time measured by the tracker, or else the
+	// time it took for the device to be seen.
+	Latency time.Duration
+}
+
+// ContactDevice asks the trackers to contact (e.g. ping) a device given its
+// identifier, and waits for it to be seen (at most the given timeout).
+func (r *Registry) ContactDevice(id string, timeout time.Duration) (ContactResult, error) {
+	r.mutex.Lock()
+	d, found := r.devices[id]
+	if !found {
+		r.mutex.Unlock()
+		return ContactResult{}, ErrNotFound
+	}
+	waiter := make(chan ContactResult, 1)
+	r.waiters[id] = append(r.waiters[id], waiter)
+	devices := []model.Device{*d}
+	r.mutex.Unlock()
+
+	start := time.Now()
+	go r.watchdog.ping(devices)
+
+	timer := time.NewTimer(min(timeout, MaxContactWait))
+	defer timer.Stop()
+	select {
+	case result := <-waiter:
+		if result.Latency == 0 {
+			result.Latency = time.Since(start)
+		}
+		return result, nil
+
+	case <-timer.C:
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.waiters[id] = slices.DeleteFunc(r.waiters[id], func(c chan ContactResult) bool { return c == waiter })
+		if len(r.waiters[id]) == 0 {
+			delete(r.waiters, id)
+		}
+		return ContactResult{}, nil
+	}
+}
+
+// notifyWaiters tells the ones waiting for a device that it has been seen (the registry must be locked).
+func (r *Registry) notifyWaiters(id string, tracker string, data map[string]string) {
+	waiters, found := r.waiters[id]
+	if !found {
+		return
+	}
+	delete(r.waiters, id)
+	result := ContactResult{Seen: true, Tracker: tracker}
+	if v, ok := data[ReportDataLatency]; ok {
+		result.Latency, _ = time.ParseDuration(v)
+	}
+	for _, c := range waiters {
+		c <- result
+	}
+}
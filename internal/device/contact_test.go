@@ -0,0 +1,71 @@
+package device
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// answeringTracker reports the devices it is asked to ping, when reachable.
+type answeringTracker struct {
+	report    ReportPresenceFunc
+	reachable bool
+}
+
+func (t *answeringTracker) Loop(f ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+	<-ctx.Done()
+	return nil
+}
+
+func (t *answeringTracker) Ping(devices []model.Device) {
+	if !t.reachable {
+		return
+	}
+	for _, d := range devices {
+		t.report([]model.DetectedInterface{{
+			Interface: d.Interfaces[0],
+			Data:      map[string]string{ReportDataLatency: "2.5ms"},
+		}})
+	}
+}
+
+func newContactRegistry(reachable bool) *Registry {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"tv": {Identifier: "tv", Status: model.StatusTracked, Interfaces: []model.Interface{
+			{Type: model.InterfaceEthernet, IPv4Address: "192.168.1.20"},
+		}},
+	}})
+	registry.watchdog.trackers["ipv4"] = &answeringTracker{report: registry.reporter("ipv4"), reachable: reachable}
+	return registry
+}
+
+func TestContactDevice(t *testing.T) {
+	registry := newContactRegistry(true)
+
+	result, err := registry.ContactDevice("tv", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, result.Seen)
+	assert.Equal(t, "ipv4", result.Tracker)
+	assert.Equal(t, 2500*time.Microsecond, result.Latency)
+	d, _ := registry.FindDevice("tv")
+	assert.True(t, d.Present)
+	assert.Empty(t, registry.waiters)
+
+	_, err = registry.ContactDevice("radio", time.Second)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestContactUnreachableDevice(t *testing.T) {
+	registry := newContactRegistry(false)
+
+	result, err := registry.ContactDevice("tv", 50*time.Millisecond)
+	assert.Nil(t, err)
+	assert.False(t, result.Seen)
+	assert.Empty(t, registry.waiters)
+}
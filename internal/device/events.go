@@ -13,32 +13,54 @@ import (
 	"github.com/touchardv/myhome-presence/pkg/model"
 )
 
-func newMQTTClient(c config.MQTT) MQTT.Client {
+// NewMQTTClientOptions returns the options for connecting to the given MQTT server.
+// The role (if any) is appended to the client identifier so that several clients
+// of the same process (e.g. the registry and a tracker) do not collide.
+func NewMQTTClientOptions(c config.MQTT, role string) *MQTT.ClientOptions {
 	server := fmt.Sprintf("tcp://%s:%d", c.Hostname, c.Port)
 	opts := MQTT.NewClientOptions().AddBroker(server)
 	opts.SetAutoReconnect(true)
-	opts.SetClientID(mqttClientID())
-	return MQTT.NewClient(opts)
+	opts.SetClientID(mqttClientID(role))
+	if len(c.Username) > 0 {
+		opts.SetUsername(c.Username)
+		opts.SetPassword(c.Password)
+	}
+	return opts
+}
+
+func newMQTTClient(c config.MQTT) MQTT.Client {
+	return MQTT.NewClient(NewMQTTClientOptions(c, ""))
 }
 
-func mqttClientID() string {
+func mqttClientID(role string) string {
 	hostname, err := os.Hostname()
 	if err != nil {
 		hostname = "unknown"
 	}
+	if len(role) > 0 {
+		return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), role)
+	}
 	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
 }
 
-func (r *Registry) connect(ctx context.Context) {
-	if r.mqttClient == nil {
+// startConnecting connects an MQTT client in the background (retrying until
+// connected, or stopped e.g. by a reload).
+func (r *Registry) startConnecting(client MQTT.Client) {
+	if client == nil {
 		return
 	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.mqttCancel = cancel
+	go connect(ctx, client)
+}
+
+func connect(ctx context.Context, client MQTT.Client) {
 	log.Info("Connecting to MQTT")
 	retry := time.NewTicker(5 * time.Second)
 
 connectLoop:
 	for {
-		if token := r.mqttClient.Connect(); token.Wait() && token.Error() != nil {
+		if token := client.Connect(); token.Wait() && token.Error() != nil {
 			log.Error("Failed to connect to MQTT: ", token.Error())
 		} else {
 			retry.Stop()
@@ -57,13 +79,13 @@ connectLoop:
 	}
 }
 
-func (r *Registry) disconnect() {
-	if r.mqttClient == nil {
+func disconnect(client MQTT.Client) {
+	if client == nil {
 		return
 	}
-	if r.mqttClient.IsConnected() {
+	if client.IsConnected() {
 		log.Info("Disconnecting from MQTT")
-		r.mqttClient.Disconnect(500)
+		client.Disconnect(500)
 		log.Info("Disconnected from MQTT")
 	}
 }
@@ -0,0 +1,183 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// ImportedDevice is a device to be imported, together with its position in the imported data.
+type ImportedDevice struct {
+	Row    int
+	Device model.Device
+}
+
+// ImportError reports why an imported device was rejected.
+type ImportError struct {
+	Row        int    `json:"row"`
+	Identifier string `json:"identifier,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportReport is the outcome of importing devices.
+type ImportReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Added   []string      `json:"added"`
+	Updated []string      `json:"updated"`
+	Removed []string      `json:"removed"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// ImportDevices adds or updates the given devices (also removing the other ones when replacing).
+// Nothing is changed when one of the devices is rejected, or when running dry.
+func (r *Registry) ImportDevices(devices []ImportedDevice, replace bool, dryRun bool) ImportReport {
+	defer r.persist()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report := ImportReport{DryRun: dryRun, Added: []string{}, Updated: []string{}, Removed: []string{}, Errors: []ImportError{}}
+	// owners are the devices (identifiers) by MAC/IP address
+	owners := make(map[string]string)
+	if !replace {
+		for _, d := range r.devices {
+			for _, itf := range d.Interfaces {
+				for _, a := range addresses(itf) {
+					owners[a] = d.Identifier
+				}
+			}
+		}
+	}
+
+	accepted, errors := checkDevices(devices, owners)
+	report.Errors = errors
+	for _, d := range accepted {
+		if _, found := r.devices[d.Identifier]; found {
+			report.Updated = append(report.Updated, d.Identifier)
+		} else {
+			report.Added = append(report.Added, d.Identifier)
+		}
+	}
+	imported := make(map[string]bool)
+	for _, i := range devices {
+		imported[i.Device.Identifier] = true
+	}
+	if replace {
+		for id := range r.devices {
+			if !imported[id] {
+				report.Removed = append(report.Removed, id)
+			}
+		}
+	}
+
+	if dryRun || len(report.Errors) > 0 {
+		return report
+	}
+	for _, id := range report.Removed {
+		d := r.devices[id]
+		delete(r.devices, id)
+		r.changed[id] = true
+		r.onRemoved(d)
+	}
+	now := time.Now()
+	for _, i := range devices {
+		r.importDevice(i.Device, now)
+	}
+	clear(r.resolved)
+	log.Infof("Imported devices: %d added, %d updated, %d removed", len(report.Added), len(report.Updated), len(report.Removed))
+	return report
+}
+
+// checkDevices returns the devices that are valid (with a unique identifier, a
+// status, valid IRKs and MAC/IP addresses not used by other devices, given the
+// owners of the addresses), and the errors of the other ones.
+func checkDevices(devices []ImportedDevice, owners map[string]string) ([]model.Device, []ImportError) {
+	accepted := []model.Device{}
+	errors := []ImportError{}
+	identifiers := make(map[string]bool)
+	for _, i := range devices {
+		d := i.Device
+		reject := func(err string) {
+			errors = append(errors, ImportError{Row: i.Row, Identifier: d.Identifier, Error: err})
+		}
+		if len(strings.TrimSpace(d.Identifier)) == 0 {
+			reject(ErrInvalidID.Error())
+			continue
+		}
+		if identifiers[d.Identifier] {
+			reject("duplicate device identifier")
+			continue
+		}
+		identifiers[d.Identifier] = true
+		if d.Status == model.StatusUndefined {
+			reject(model.ErrMissingDeviceStatus.Error())
+			continue
+		}
+		if err := validateIRKs(d); err != nil {
+			reject(err.Error())
+			continue
+		}
+		clash := false
+		for _, itf := range d.Interfaces {
+			for _, a := range addresses(itf) {
+				if owner, found := owners[a]; found && owner != d.Identifier {
+					reject(fmt.Sprintf("address %s already used by device %s", a, owner))
+					clash = true
+				}
+			}
+		}
+		if clash {
+			continue
+		}
+		for _, itf := range d.Interfaces {
+			for _, a := range addresses(itf) {
+				owners[a] = d.Identifier
+			}
+		}
+		accepted = append(accepted, d)
+	}
+	return accepted, errors
+}
+
+func (r *Registry) importDevice(ud model.Device, now time.Time) {
+	d, found := r.devices[ud.Identifier]
+	if !found {
+		d = &model.Device{
+			Identifier:  ud.Identifier,
+			Description: ud.Description,
+			Interfaces:  ud.Interfaces,
+			Properties:  ud.Properties,
+			Status:      ud.Status,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		r.devices[d.Identifier] = d
+		r.changed[d.Identifier] = true
+		r.onAdded(d)
+		return
+	}
+	// identifier, creation date and presence state are left untouched
+	d.Description = ud.Description
+	d.Interfaces = ud.Interfaces
+	d.Properties = ud.Properties
+	previousStatus := d.Status
+	d.Status = ud.Status
+	previousUpdatedAt := d.UpdatedAt
+	d.UpdatedAt = now
+	r.changed[d.Identifier] = true
+	r.onUpdated(d, previousStatus, previousUpdatedAt)
+}
+
+// addresses returns the (normalized) addresses identifying an interface, e.g. "mac aa:bb:cc:dd:ee:ff".
+func addresses(itf model.Interface) []string {
+	a := []string{}
+	if len(itf.MACAddress) > 0 {
+		a = append(a, "mac "+strings.ToLower(itf.MACAddress))
+	}
+	if len(itf.IPv4Address) > 0 {
+		a = append(a, "ip "+itf.IPv4Address)
+	}
+	return a
+}
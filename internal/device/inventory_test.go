@@ -0,0 +1,85 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func newInventoryRegistry() *Registry {
+	return NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"phone": {Identifier: "phone", Status: model.StatusTracked, Present: true,
+			Interfaces: []model.Interface{{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:FF"}}},
+		"tv": {Identifier: "tv", Status: model.StatusIgnored,
+			Interfaces: []model.Interface{{Type: model.InterfaceEthernet, IPv4Address: "192.168.1.10"}}},
+	}})
+}
+
+func TestImportDevicesMerge(t *testing.T) {
+	registry := newInventoryRegistry()
+	report := registry.ImportDevices([]ImportedDevice{
+		{Row: 1, Device: model.Device{Identifier: "phone", Description: "My phone", Status: model.StatusTracked,
+			Interfaces: []model.Interface{{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}}}},
+		{Row: 2, Device: model.Device{Identifier: "laptop", Status: model.StatusTracked}},
+	}, false, false)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []string{"laptop"}, report.Added)
+	assert.Equal(t, []string{"phone"}, report.Updated)
+	assert.Empty(t, report.Removed)
+
+	d, err := registry.FindDevice("phone")
+	assert.Nil(t, err)
+	assert.Equal(t, "My phone", d.Description)
+	assert.True(t, d.Present)
+	_, err = registry.FindDevice("tv")
+	assert.Nil(t, err)
+	_, err = registry.FindDevice("laptop")
+	assert.Nil(t, err)
+}
+
+func TestImportDevicesReplace(t *testing.T) {
+	registry := newInventoryRegistry()
+	report := registry.ImportDevices([]ImportedDevice{
+		{Row: 1, Device: model.Device{Identifier: "laptop", Status: model.StatusTracked,
+			Interfaces: []model.Interface{{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}}}},
+	}, true, false)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []string{"laptop"}, report.Added)
+	assert.ElementsMatch(t, []string{"phone", "tv"}, report.Removed)
+	assert.Equal(t, 1, len(registry.GetDevices(model.StatusUndefined)))
+}
+
+func TestImportDevicesErrors(t *testing.T) {
+	registry := newInventoryRegistry()
+	report := registry.ImportDevices([]ImportedDevice{
+		{Row: 1, Device: model.Device{Identifier: "laptop", Status: model.StatusTracked}},
+		{Row: 2, Device: model.Device{Identifier: "laptop", Status: model.StatusTracked}},
+		{Row: 3, Device: model.Device{Identifier: "", Status: model.StatusTracked}},
+		{Row: 4, Device: model.Device{Identifier: "watch"}},
+		{Row: 5, Device: model.Device{Identifier: "tablet", Status: model.StatusTracked,
+			Interfaces: []model.Interface{{Type: model.InterfaceEthernet, IPv4Address: "192.168.1.10"}}}},
+		{Row: 6, Device: model.Device{Identifier: "tag", Status: model.StatusTracked,
+			Interfaces: []model.Interface{{Type: model.InterfaceBluetooth, IRK: "bad"}}}},
+	}, false, false)
+	assert.Equal(t, []ImportError{
+		{Row: 2, Identifier: "laptop", Error: "duplicate device identifier"},
+		{Row: 3, Error: ErrInvalidID.Error()},
+		{Row: 4, Identifier: "watch", Error: model.ErrMissingDeviceStatus.Error()},
+		{Row: 5, Identifier: "tablet", Error: "address ip 192.168.1.10 already used by device tv"},
+		{Row: 6, Identifier: "tag", Error: ErrInvalidIRK.Error()},
+	}, report.Errors)
+	assert.Equal(t, 2, len(registry.GetDevices(model.StatusUndefined)))
+}
+
+func TestImportDevicesDryRun(t *testing.T) {
+	registry := newInventoryRegistry()
+	report := registry.ImportDevices([]ImportedDevice{
+		{Row: 1, Device: model.Device{Identifier: "laptop", Status: model.StatusTracked}},
+	}, true, true)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []string{"laptop"}, report.Added)
+	assert.ElementsMatch(t, []string{"phone", "tv"}, report.Removed)
+	assert.Equal(t, 2, len(registry.GetDevices(model.StatusUndefined)))
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"maps"
 
@@ -20,8 +24,16 @@ var (
 	ErrNotFound       = errors.New("device not found")
 	ErrInvalidID      = errors.New("invalid device identifier")
 	ErrIDAlreadyTaken = errors.New("device identifier already taken")
+	ErrInvalidIRK     = errors.New("invalid IRK (expecting 32 hexadecimal digits)")
+	ErrNoSuchTracker  = errors.New("no such tracker")
 )
 
+// PropertyMinRSSI is the device property defining the minimum signal strength
+// (in dBm) of a sighting for the device to be considered present.
+const PropertyMinRSSI = "min_rssi"
+
+const saveInterval = 5 * time.Minute
+
 // Registry maintains the status of all tracked devices
 // together with their presence status.
 type Registry struct {
@@ -31,6 +43,28 @@ type Registry struct {
 	mqttClient MQTT.Client
 	mqttTopic  string
 	watchdog   *watchdog
+	actions    map[string]Action
+	waiters    map[string][]chan ContactResult
+
+	// ctx is the context the registry was started with, and mqttCancel stops
+	// connecting the current MQTT client (e.g. replaced on reload).
+	ctx        context.Context
+	mqttCancel context.CancelFunc
+	reloading  sync.Mutex
+
+	// resolved caches the devices (identifiers) owning the resolvable private
+	// addresses seen so far (or "" when none).
+	resolved map[string]string
+
+	// changed records the devices (identifiers) changed or removed since they were
+	// last persisted, which is done once the lock released, by persist (serialized
+	// by the persisting mutex), as the storage syncs the changes.
+	changed    map[string]bool
+	persisting sync.Mutex
+
+	// departed records until when the sightings of the devices that reported
+	// their departure are ignored, unless identifying the device (e.g. a check-in).
+	departed map[string]time.Time
 }
 
 // NewRegistry builds a new device registry.
@@ -43,18 +77,25 @@ func NewRegistry(cfg config.Config) *Registry {
 	if cfg.MQTTServer.Enabled {
 		mqttClient = newMQTTClient(cfg.MQTTServer)
 	}
-	return &Registry{
+	r := &Registry{
 		cfg:        cfg,
 		devices:    devices,
 		mutex:      &sync.RWMutex{},
 		mqttClient: mqttClient,
 		mqttTopic:  cfg.MQTTServer.Topic,
 		watchdog:   newWatchDog(cfg),
+		waiters:    make(map[string][]chan ContactResult),
+		resolved:   make(map[string]string),
+		departed:   make(map[string]time.Time),
+		changed:    make(map[string]bool),
 	}
+	r.actions = r.newActions()
+	return r
 }
 
 // AddDevice adds a new device to the registry.
 func (r *Registry) AddDevice(d model.Device) error {
+	defer r.persist()
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -67,6 +108,9 @@ func (r *Registry) AddDevice(d model.Device) error {
 	if d.Status == model.StatusUndefined {
 		return model.ErrMissingDeviceStatus
 	}
+	if err := validateIRKs(d); err != nil {
+		return err
+	}
 
 	d.CreatedAt = time.Now()
 	// reset the presence state
@@ -74,43 +118,58 @@ func (r *Registry) AddDevice(d model.Device) error {
 	d.LastSeenAt = time.Time{}
 	d.Present = false
 	r.devices[d.Identifier] = &d
+	clear(r.resolved)
+	r.changed[d.Identifier] = true
 	r.onAdded(&d)
 	log.Info("Device added: ", d.Identifier)
 	return nil
 }
 
+// DeviceActions returns the names of the actions that can be executed on a device given its identifier.
+func (r *Registry) DeviceActions(id string) ([]string, error) {
+	d, err := r.FindDevice(id)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name, a := range r.actions {
+		if a.Supports(d) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
 // ExecuteDeviceAction executes an action on a device given its identifier.
 func (r *Registry) ExecuteDeviceAction(id string, action string) error {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	d, err := r.FindDevice(id)
+	if err != nil {
+		return err
+	}
+	a, found := r.actions[action]
+	if !found || !a.Supports(d) {
+		return model.ErrInvalidDeviceAction
+	}
+	return a.Execute(d)
+}
 
-	if d, found := r.devices[id]; found {
-		switch action {
-		case "contact":
-			devices := []model.Device{*d}
-			r.watchdog.ping(devices)
-
-		case "ignore":
-			previousStatus := d.Status
-			d.Status = model.StatusIgnored
-			previousUpdatedAt := d.UpdatedAt
-			d.UpdatedAt = time.Now()
-			r.onUpdated(d, previousStatus, previousUpdatedAt)
-
-		case "track":
-			previousStatus := d.Status
-			d.Status = model.StatusTracked
-			previousUpdatedAt := d.UpdatedAt
-			d.UpdatedAt = time.Now()
-			r.onUpdated(d, previousStatus, previousUpdatedAt)
-
-		default:
-			return model.ErrInvalidDeviceAction
-		}
+func (r *Registry) updateStatus(id string, status model.Status) error {
+	defer r.persist()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-		return nil
+	d, found := r.devices[id]
+	if !found {
+		return ErrNotFound
 	}
-	return ErrNotFound
+	previousStatus := d.Status
+	d.Status = status
+	previousUpdatedAt := d.UpdatedAt
+	d.UpdatedAt = time.Now()
+	r.changed[id] = true
+	r.onUpdated(d, previousStatus, previousUpdatedAt)
+	return nil
 }
 
 // FindDevice lookups a device given its identifier.
@@ -174,41 +233,91 @@ func description(optData map[string]string, ts time.Time) string {
 
 func identifier(optData map[string]string) string {
 	if optData != nil {
-		if id, ok := optData[ReportDataSuggestedIdentifier]; ok {
-			return strings.ToLower(id)
+		if id := toIdentifier(optData[ReportDataSuggestedIdentifier]); len(id) > 0 {
+			return id
 		}
 	}
 	return "unidentified-device"
 }
 
+// toIdentifier turns a (host) name into a suitable device identifier, usable in
+// URLs (e.g. "John's Phone" => "john-s-phone").
+func toIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteRune('-')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// lookupDevice returns the device having a given interface: by beacon identity,
+// by MAC address, or else by IP address.
 func (r *Registry) lookupDevice(itf model.Interface) *model.Device {
-	for _, d := range r.devices {
-		for _, di := range d.Interfaces {
-			match := true
-			if itf.Type != model.InterfaceUnknown {
-				match = match && (itf.Type == di.Type)
-			}
-			if len(itf.MACAddress) > 0 {
-				match = match && (strings.EqualFold(itf.MACAddress, di.MACAddress))
+	if len(itf.BeaconID) > 0 {
+		for _, d := range r.devices {
+			for _, di := range d.Interfaces {
+				if strings.EqualFold(itf.BeaconID, di.BeaconID) {
+					return d
+				}
 			}
-			if len(itf.IPv4Address) > 0 {
-				match = match && (itf.IPv4Address == di.IPv4Address)
+		}
+	}
+	// the MAC address identifies an interface, whose IP address may change (e.g. DHCP)
+	if len(itf.MACAddress) > 0 {
+		for _, d := range r.devices {
+			for _, di := range d.Interfaces {
+				if sameType(itf, di) && strings.EqualFold(itf.MACAddress, di.MACAddress) {
+					return d
+				}
 			}
-			if match {
-				return d
+		}
+	}
+	// the IP address only when either of the MAC addresses is unknown
+	if len(itf.IPv4Address) > 0 {
+		for _, d := range r.devices {
+			for _, di := range d.Interfaces {
+				if sameType(itf, di) && itf.IPv4Address == di.IPv4Address &&
+					(len(itf.MACAddress) == 0 || len(di.MACAddress) == 0) {
+					return d
+				}
 			}
 		}
 	}
+	if (itf.Type == model.InterfaceBluetooth || itf.Type == model.InterfaceUnknown) && isResolvablePrivateAddress(itf.MACAddress) {
+		return r.resolveDevice(itf.MACAddress)
+	}
 	return nil
 }
 
+// HTTPHandlers returns the HTTP handlers of the trackers receiving sightings
+// from HTTP requests, by tracker name.
+func (r *Registry) HTTPHandlers() map[string]http.Handler {
+	r.watchdog.mutex.RLock()
+	defer r.watchdog.mutex.RUnlock()
+	handlers := make(map[string]http.Handler)
+	for name, t := range r.watchdog.trackers {
+		if h, ok := t.(HTTPTracker); ok {
+			handlers[name] = h
+		}
+	}
+	return handlers
+}
+
 // RemoveDevice removes a device.
 func (r *Registry) RemoveDevice(id string) error {
+	defer r.persist()
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if d, found := r.devices[id]; found {
 		delete(r.devices, id)
+		delete(r.departed, id)
+		clear(r.resolved)
+		r.changed[id] = true
 		r.onRemoved(d)
 		log.Info("Device removed: ", id)
 		return nil
@@ -217,16 +326,76 @@ func (r *Registry) RemoveDevice(id string) error {
 }
 
 func (r *Registry) reportPresence(itfs []model.DetectedInterface) {
+	r.reportPresenceBy("", itfs)
+}
+
+// reporter returns the function reporting the presence of devices on behalf of a tracker.
+func (r *Registry) reporter(tracker string) ReportPresenceFunc {
+	return func(itfs []model.DetectedInterface) {
+		r.reportPresenceBy(tracker, itfs)
+	}
+}
+
+func (r *Registry) reportPresenceBy(tracker string, itfs []model.DetectedInterface) {
+	defer r.persist()
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	for _, detected := range itfs {
 		itf := detected.Interface
 		optData := detected.Data
-		d := r.lookupDevice(itf)
+		var d *model.Device
+		if len(detected.DeviceID) > 0 {
+			if d = r.devices[detected.DeviceID]; d == nil {
+				log.Debug("Ignored sighting of an unknown device: ", detected.DeviceID)
+				continue
+			}
+		} else {
+			d = r.lookupDevice(itf)
+		}
+		if detected.Departed {
+			if d != nil {
+				r.recordDeparture(d, optData)
+			}
+			if d != nil && d.Present {
+				d.Present = false
+				d.UpdatedAt = time.Now()
+				clearMeasurements(d)
+				r.changed[d.Identifier] = true
+				r.onPresenceUpdated(d)
+			}
+			continue
+		}
+		now := time.Now()
+		if d != nil {
+			if until, found := r.departed[d.Identifier]; found {
+				if len(detected.DeviceID) == 0 && now.Before(until) {
+					log.Debugf("Ignored a sighting of: %s (departed)", d.Identifier)
+					continue
+				}
+				delete(r.departed, d.Identifier)
+			}
+		}
+		seenAt := now
+		if !detected.LastSeenAt.IsZero() && detected.LastSeenAt.Before(now) {
+			seenAt = detected.LastSeenAt
+		}
+		if d != nil && tooWeak(d, optData) {
+			// e.g. seen from the street: keep the measurements but not the presence
+			if d.Properties == nil {
+				d.Properties = make(map[string]string)
+			}
+			maps.Copy(d.Properties, optData)
+			r.changed[d.Identifier] = true
+			log.Debugf("Ignored a weak sighting of: %s (RSSI %s dBm)", d.Identifier, optData[ReportDataRSSI])
+			continue
+		}
 		if d == nil {
 			d = r.newDevice(itf, optData)
+			d.FirstSeenAt = seenAt
+			d.LastSeenAt = seenAt
 			r.devices[d.Identifier] = d
+			r.changed[d.Identifier] = true
 			log.Infof("Discovered a new device: %s from interface: mac=%s ip=%s type=%s", d.Identifier, itf.MACAddress, itf.IPv4Address, itf.Type)
 		} else {
 			// Merge device properties
@@ -237,42 +406,134 @@ func (r *Registry) reportPresence(itfs []model.DetectedInterface) {
 				maps.Copy(d.Properties, optData)
 			}
 
-			now := time.Now()
 			if !d.Present {
-				d.FirstSeenAt = now
-				d.LastSeenAt = now
+				d.FirstSeenAt = seenAt
+				d.LastSeenAt = seenAt
 				d.Present = true
 				d.UpdatedAt = now
+				r.changed[d.Identifier] = true
 				r.onPresenceUpdated(d)
 			} else {
-				d.LastSeenAt = now
+				if seenAt.After(d.LastSeenAt) {
+					d.LastSeenAt = seenAt
+				}
 				previousUpdatedAt := d.UpdatedAt
 				d.UpdatedAt = now
 				r.onUpdated(d, d.Status, previousUpdatedAt)
 			}
+			r.notifyWaiters(d.Identifier, tracker, optData)
+		}
+	}
+}
+
+func sameType(itf model.Interface, di model.Interface) bool {
+	return itf.Type == model.InterfaceUnknown || itf.Type == di.Type
+}
+
+// recordDeparture records the departure of a device, whose sightings are then
+// ignored for the grace period of the departure, if any.
+func (r *Registry) recordDeparture(d *model.Device, data map[string]string) {
+	v, found := data[ReportDataGracePeriod]
+	if !found {
+		return
+	}
+	gracePeriod, err := time.ParseDuration(v)
+	if err != nil || gracePeriod <= 0 {
+		return
+	}
+	r.departed[d.Identifier] = time.Now().Add(gracePeriod)
+}
+
+// measurements are the report data describing the current signal of a device,
+// which no longer apply once it is absent.
+var measurements = []string{ReportDataLatency, ReportDataPacketLoss, ReportDataRSSI, ReportDataDistance}
+
+func clearMeasurements(d *model.Device) {
+	for _, k := range measurements {
+		delete(d.Properties, k)
+	}
+}
+
+// tooWeak tells whether the signal strength of a sighting is below the minimum
+// RSSI (i.e. the "min_rssi" property) of the device.
+func tooWeak(d *model.Device, data map[string]string) bool {
+	v, found := d.Properties[PropertyMinRSSI]
+	if !found {
+		return false
+	}
+	minRSSI, err := strconv.Atoi(v)
+	if err != nil {
+		return false
+	}
+	rssi, err := strconv.Atoi(data[ReportDataRSSI])
+	if err != nil {
+		return false
+	}
+	return rssi < minRSSI
+}
+
+// persist writes the changed (or removed) devices to the storage, without
+// holding the registry lock.
+func (r *Registry) persist() {
+	r.persisting.Lock()
+	defer r.persisting.Unlock()
+
+	r.mutex.Lock()
+	cfg := r.cfg
+	changed := make(map[string]*model.Device, len(r.changed))
+	for id := range r.changed {
+		if d, found := r.devices[id]; found {
+			c := snapshot(d)
+			changed[id] = &c
+		} else {
+			changed[id] = nil
+		}
+	}
+	clear(r.changed)
+	r.mutex.Unlock()
+
+	for id, d := range changed {
+		if d == nil {
+			cfg.Delete(id)
+		} else {
+			cfg.Put(*d)
 		}
 	}
 }
 
 func (r *Registry) saveDevices() {
+	r.persisting.Lock()
+	defer r.persisting.Unlock()
+
 	r.mutex.RLock()
+	cfg := r.cfg
 	devices := make([]model.Device, 0, len(r.devices))
 	for d := range maps.Values(r.devices) {
-		devices = append(devices, *d)
+		devices = append(devices, snapshot(d))
 	}
 	r.mutex.RUnlock()
-	r.cfg.Save(devices)
+	cfg.Save(devices)
 }
 
+// snapshot returns a copy of a device, not sharing its (mutable) interfaces and properties.
+func snapshot(d *model.Device) model.Device {
+	c := *d
+	c.Interfaces = slices.Clone(d.Interfaces)
+	c.Properties = maps.Clone(d.Properties)
+	return c
+}
+
+// saveLoop periodically saves the devices, e.g. for persisting the last seen
+// dates (the other changes are saved as they happen).
 func (r *Registry) saveLoop(ctx context.Context) {
-	save := time.NewTimer(1 * time.Hour)
+	save := time.NewTimer(saveInterval)
 
 saveLoop:
 	for {
 		select {
 		case <-save.C:
 			r.saveDevices()
-			save.Reset(1 * time.Hour)
+			save.Reset(saveInterval)
 
 		case <-ctx.Done():
 			save.Stop()
@@ -284,7 +545,8 @@ saveLoop:
 // Start activates the tracking of devices.
 func (r *Registry) Start(ctx context.Context) {
 	log.Info("Starting: registry")
-	go r.connect(ctx)
+	r.ctx = ctx
+	r.startConnecting(r.mqttClient)
 	go r.saveLoop(ctx)
 	go r.watchdog.loop(r, ctx)
 }
@@ -293,13 +555,15 @@ func (r *Registry) Start(ctx context.Context) {
 func (r *Registry) Stop() {
 	log.Info("Stopping: registry")
 	r.watchdog.stop()
-	r.disconnect()
+	disconnect(r.mqttClient)
 	r.saveDevices()
+	r.cfg.Close()
 	log.Info("Stopped: registry")
 }
 
 // UpdateDevice updates an existing device.
 func (r *Registry) UpdateDevice(id string, ud model.Device) (model.Device, error) {
+	defer r.persist()
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -313,20 +577,26 @@ func (r *Registry) UpdateDevice(id string, ud model.Device) (model.Device, error
 	if ud.Status == model.StatusUndefined {
 		return model.Device{}, model.ErrMissingDeviceStatus
 	}
+	if err := validateIRKs(ud); err != nil {
+		return model.Device{}, err
+	}
 
 	// identifier, creation date and presence state are left untouched
 	d.Description = ud.Description
 	d.Interfaces = ud.Interfaces
+	clear(r.resolved)
 	d.Properties = ud.Properties
 	previousStatus := d.Status
 	d.Status = ud.Status
 	previousUpdatedAt := d.UpdatedAt
 	d.UpdatedAt = time.Now()
+	r.changed[id] = true
 	r.onUpdated(d, previousStatus, previousUpdatedAt)
 	return *d, nil
 }
 
 func (r *Registry) UpdateDevicesPresence(t time.Time) {
+	defer r.persist()
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -340,6 +610,8 @@ func (r *Registry) UpdateDevicesPresence(t time.Time) {
 			if elapsedMinutes >= 10 {
 				if d.Present {
 					d.Present = false
+					clearMeasurements(d)
+					r.changed[d.Identifier] = true
 					r.onPresenceUpdated(d)
 				}
 			}
@@ -348,6 +620,7 @@ func (r *Registry) UpdateDevicesPresence(t time.Time) {
 
 	for _, id := range removedIDs {
 		delete(r.devices, id)
+		r.changed[id] = true
 		log.Debug("Discovered device automatically removed: ", id)
 	}
 }
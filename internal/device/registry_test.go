@@ -2,6 +2,8 @@ package device
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -151,6 +153,14 @@ func TestNewDevice(t *testing.T) {
 	assert.True(t, strings.HasPrefix(d.Identifier, "foo-")) // ID exists => suffix is appended
 }
 
+func TestToIdentifier(t *testing.T) {
+	assert.Equal(t, "john-s-phone", toIdentifier("John's  Phone "))
+	assert.Equal(t, "tv", toIdentifier("TV"))
+	assert.Equal(t, "nas-local", toIdentifier("nas/local"))
+	assert.Equal(t, "", toIdentifier(""))
+	assert.Equal(t, "unidentified-device", identifier(map[string]string{ReportDataSuggestedIdentifier: "--"}))
+}
+
 func TestLookupDevice(t *testing.T) {
 	registry := NewRegistry(cfg)
 
@@ -170,6 +180,51 @@ func TestLookupDevice(t *testing.T) {
 	assert.Nil(t, d)
 }
 
+func TestLookupDeviceWithNewIPAddress(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"laptop": {Identifier: "laptop", Interfaces: []model.Interface{
+			{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:00:00:01", IPv4Address: "192.168.1.10"},
+		}, Status: model.StatusTracked},
+		"printer": {Identifier: "printer", Interfaces: []model.Interface{
+			{Type: model.InterfaceEthernet, IPv4Address: "192.168.1.20"},
+		}, Status: model.StatusTracked},
+	}})
+
+	// same MAC address, new (DHCP) IP address
+	d := registry.lookupDevice(model.Interface{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:00:00:01", IPv4Address: "192.168.1.11"})
+	assert.NotNil(t, d)
+	assert.Equal(t, "laptop", d.Identifier)
+
+	// the previous IP address of another interface
+	d = registry.lookupDevice(model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:00:00:02", IPv4Address: "192.168.1.10"})
+	assert.Nil(t, d)
+
+	// configured without MAC address
+	d = registry.lookupDevice(model.Interface{Type: model.InterfaceEthernet, MACAddress: "aa:bb:cc:00:00:03", IPv4Address: "192.168.1.20"})
+	assert.NotNil(t, d)
+	assert.Equal(t, "printer", d.Identifier)
+
+	// reported without MAC address (e.g. ping)
+	d = registry.lookupDevice(model.Interface{Type: model.InterfaceUnknown, IPv4Address: "192.168.1.10"})
+	assert.NotNil(t, d)
+	assert.Equal(t, "laptop", d.Identifier)
+}
+
+func TestLookupDeviceConfiguredWithMACAddressOnly(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"phone": {Identifier: "phone", Interfaces: []model.Interface{
+			{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:00:00:01"},
+		}, Status: model.StatusTracked},
+	}})
+
+	d := registry.lookupDevice(model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:00:00:01", IPv4Address: "192.168.1.12"})
+	assert.NotNil(t, d)
+	assert.Equal(t, "phone", d.Identifier)
+
+	d = registry.lookupDevice(model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:00:00:02", IPv4Address: "192.168.1.12"})
+	assert.Nil(t, d)
+}
+
 func TestRemoveDevice(t *testing.T) {
 	registry := NewRegistry(cfg)
 
@@ -179,11 +234,19 @@ func TestRemoveDevice(t *testing.T) {
 }
 
 func TestRegistryStartStop(t *testing.T) {
-	registry := NewRegistry(cfg)
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte{}, 0644))
+	registry := NewRegistry(config.Retrieve(dir, dir))
+	registry.AddDevice(model.Device{Identifier: "foo", Status: model.StatusTracked})
 	ctx, cancel := context.WithCancel(context.Background())
 	registry.Start(ctx)
 	cancel()
 	registry.Stop()
+
+	// the devices are saved in the data location on stop
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	assert.Equal(t, 1, len(cfg.Devices))
 }
 
 func TestUpdateDevice(t *testing.T) {
@@ -234,10 +297,178 @@ func TestUpdateTrackedDevicePresence(t *testing.T) {
 	device.LastSeenAt = time.Now()
 	device.Present = true
 	device.Status = model.StatusTracked
+	device.Properties = map[string]string{ReportDataRSSI: "-60"}
+	defer func() { device.Properties = nil }()
 
 	registry.UpdateDevicesPresence(time.Now().Add(5 * time.Minute))
 	assert.True(t, device.Present)
 
 	registry.UpdateDevicesPresence(time.Now().Add(11 * time.Minute))
 	assert.False(t, device.Present)
+	assert.Empty(t, device.Properties)
+}
+
+func TestReportDepartureOfAnExistingDevice(t *testing.T) {
+	itf := model.Interface{Type: model.InterfaceWifi, IPv4Address: "1.2.3.4"}
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"foo": {Identifier: "foo", Interfaces: []model.Interface{itf}, Status: model.StatusTracked},
+	}})
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, Data: map[string]string{ReportDataLatency: "2ms", "Hostname": "foo"}}})
+	d, _ := registry.FindDevice("foo")
+	assert.True(t, d.Present)
+	assert.Equal(t, "2ms", d.Properties[ReportDataLatency])
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, Departed: true}})
+	d, _ = registry.FindDevice("foo")
+	assert.False(t, d.Present)
+	assert.False(t, d.LastSeenAt.IsZero())
+	// the measurements no longer apply
+	assert.Equal(t, map[string]string{"Hostname": "foo"}, d.Properties)
+}
+
+func TestReportDepartureWithGracePeriod(t *testing.T) {
+	itf := model.Interface{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:ff"}
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"foo": {Identifier: "foo", Interfaces: []model.Interface{itf}, Status: model.StatusTracked},
+	}})
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "foo", Departed: true, Data: map[string]string{ReportDataGracePeriod: "10m"}}})
+	d, _ := registry.FindDevice("foo")
+	assert.False(t, d.Present)
+
+	// e.g. still seen by Bluetooth while leaving
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+	d, _ = registry.FindDevice("foo")
+	assert.False(t, d.Present)
+
+	// e.g. checking in
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "foo"}})
+	d, _ = registry.FindDevice("foo")
+	assert.True(t, d.Present)
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+	d, _ = registry.FindDevice("foo")
+	assert.True(t, d.Present)
+
+	// once the grace period is over
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "foo", Departed: true, Data: map[string]string{ReportDataGracePeriod: "10m"}}})
+	registry.departed["foo"] = time.Now().Add(-time.Second)
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+	d, _ = registry.FindDevice("foo")
+	assert.True(t, d.Present)
+	assert.Equal(t, 0, len(registry.departed))
+}
+
+func TestReportDepartureOfAnUnknownDevice(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{}})
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: model.Interface{Type: model.InterfaceWifi, IPv4Address: "1.2.3.4"}, Departed: true}})
+
+	devices := registry.GetDevices(model.StatusUndefined)
+	assert.Equal(t, 0, len(devices))
+}
+
+func TestReportPresenceWithLastSeenAt(t *testing.T) {
+	itf := model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{}})
+	seenAt := time.Now().Add(-2 * time.Minute).Truncate(time.Second)
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, LastSeenAt: seenAt}})
+	devices := registry.GetDevices(model.StatusUndefined)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, seenAt, devices[0].FirstSeenAt)
+	assert.Equal(t, seenAt, devices[0].LastSeenAt)
+
+	// an older sighting does not move the last seen date backwards
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, LastSeenAt: seenAt.Add(-time.Minute)}})
+	devices = registry.GetDevices(model.StatusUndefined)
+	assert.Equal(t, seenAt, devices[0].LastSeenAt)
+
+	// a sighting in the future is reported as now
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, LastSeenAt: time.Now().Add(time.Hour)}})
+	devices = registry.GetDevices(model.StatusUndefined)
+	assert.True(t, devices[0].LastSeenAt.Before(time.Now().Add(time.Second)))
+	assert.True(t, devices[0].LastSeenAt.After(seenAt))
+}
+
+func TestReportPresenceWithDeviceID(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"phone": {Identifier: "phone", Status: model.StatusTracked},
+	}})
+
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "phone", Data: map[string]string{"Room": "kitchen"}}})
+	d, _ := registry.FindDevice("phone")
+	assert.True(t, d.Present)
+	assert.Equal(t, "kitchen", d.Properties["Room"])
+
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "phone", Departed: true}})
+	d, _ = registry.FindDevice("phone")
+	assert.False(t, d.Present)
+
+	// no device gets discovered
+	registry.reportPresence([]model.DetectedInterface{{DeviceID: "tablet"}})
+	assert.Equal(t, 1, len(registry.GetDevices(model.StatusUndefined)))
+}
+
+func TestReportPresenceBelowMinRSSI(t *testing.T) {
+	itf := model.Interface{Type: model.InterfaceBluetooth, MACAddress: "bb:77:33:00:00:00"}
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"phone": {Identifier: "phone", Status: model.StatusTracked, Interfaces: []model.Interface{itf},
+			Properties: map[string]string{PropertyMinRSSI: "-70"}},
+	}})
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, Data: map[string]string{ReportDataRSSI: "-85"}}})
+	d, _ := registry.FindDevice("phone")
+	assert.False(t, d.Present)
+	assert.Equal(t, "-85", d.Properties[ReportDataRSSI])
+
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf, Data: map[string]string{ReportDataRSSI: "-60"}}})
+	d, _ = registry.FindDevice("phone")
+	assert.True(t, d.Present)
+	assert.Equal(t, "-60", d.Properties[ReportDataRSSI])
+
+	// sightings without RSSI are not filtered
+	assert.False(t, tooWeak(&d, map[string]string{}))
+}
+
+func TestLookupDeviceByBeaconID(t *testing.T) {
+	beacon := "ibeacon:f7826da6-4fa2-4e98-8024-bc5b71e0893e:100:1"
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"keys": {Identifier: "keys", Status: model.StatusTracked, Interfaces: []model.Interface{
+			{Type: model.InterfaceBluetooth, BeaconID: beacon},
+		}},
+	}})
+
+	d := registry.lookupDevice(model.Interface{Type: model.InterfaceBluetooth, MACAddress: "5a:bb:cc:dd:ee:01", BeaconID: strings.ToUpper(beacon)})
+	assert.NotNil(t, d)
+	assert.Equal(t, "keys", d.Identifier)
+
+	d = registry.lookupDevice(model.Interface{Type: model.InterfaceBluetooth, MACAddress: "5a:bb:cc:dd:ee:01", BeaconID: "ibeacon:other:1:1"})
+	assert.Nil(t, d)
+}
+
+func TestWriteThrough(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("storage:\n  backend: bolt\n"), 0644))
+	registry := NewRegistry(config.Retrieve(dir, dir))
+
+	assert.Nil(t, registry.AddDevice(model.Device{Identifier: "phone", Status: model.StatusTracked}))
+	registry.reportPresence([]model.DetectedInterface{{Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}}})
+	assert.Nil(t, registry.ExecuteDeviceAction("phone", "ignore"))
+	assert.Empty(t, registry.changed)
+	registry.cfg.Close()
+
+	// the changes are persisted as they happen (i.e. not only on shutdown)
+	cfg := config.Retrieve(dir, dir)
+	defer cfg.Close()
+	assert.Equal(t, 2, len(cfg.Devices))
+	assert.Equal(t, model.StatusIgnored, cfg.Devices["phone"].Status)
+
+	registry = NewRegistry(cfg)
+	assert.Nil(t, registry.RemoveDevice("phone"))
+	cfg.Close()
+	cfg = config.Retrieve(dir, dir)
+	defer cfg.Close()
+	assert.Equal(t, 1, len(cfg.Devices))
 }
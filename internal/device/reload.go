@@ -0,0 +1,84 @@
+package device
+
+import (
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+)
+
+// ReloadReport tells how a configuration reload was applied.
+type ReloadReport struct {
+	Started   []string `json:"started"`
+	Stopped   []string `json:"stopped"`
+	Restarted []string `json:"restarted"`
+
+	MQTTReconnected bool `json:"mqtt_reconnected"`
+
+	// RestartRequired lists the changed settings that only apply on restart.
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Reload re-reads the configuration file, then restarts the trackers whose
+// settings changed and reconnects to MQTT when needed; the devices (and their
+// presence) are left untouched.
+func (r *Registry) Reload() (config.Config, ReloadReport, error) {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	r.mutex.RLock()
+	current := r.cfg
+	r.mutex.RUnlock()
+	cfg, err := current.Reload()
+	if err != nil {
+		return current, ReloadReport{}, err
+	}
+	report, err := r.watchdog.reconfigure(cfg.Trackers)
+	if err != nil {
+		return current, report, err
+	}
+	report.RestartRequired = []string{}
+	if cfg.MQTTServer != current.MQTTServer {
+		r.reconnect(cfg.MQTTServer)
+		report.MQTTReconnected = true
+	}
+	if cfg.Storage != current.Storage {
+		report.RestartRequired = append(report.RestartRequired, "storage")
+		cfg.Storage = current.Storage
+	}
+
+	r.mutex.Lock()
+	r.cfg = cfg
+	r.mutex.Unlock()
+	log.Infof("Reloaded the configuration: trackers started %v, stopped %v, restarted %v",
+		report.Started, report.Stopped, report.Restarted)
+	return cfg, report, nil
+}
+
+// Config returns the current configuration.
+func (r *Registry) Config() config.Config {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cfg
+}
+
+// reconnect replaces the MQTT client, for publishing the events with new settings.
+func (r *Registry) reconnect(c config.MQTT) {
+	var client MQTT.Client
+	if c.Enabled {
+		client = newMQTTClient(c)
+	}
+	r.mutex.Lock()
+	previous := r.mqttClient
+	r.mqttClient = client
+	r.mqttTopic = c.Topic
+	r.mutex.Unlock()
+
+	if r.mqttCancel != nil {
+		r.mqttCancel()
+		r.mqttCancel = nil
+	}
+	disconnect(previous)
+	if r.ctx != nil {
+		r.startConnecting(client)
+	}
+}
@@ -0,0 +1,73 @@
+package device
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// runningTrackers counts the loops of the "counting" trackers being run.
+var runningTrackers atomic.Int32
+
+type countingTracker struct{}
+
+func (t *countingTracker) Loop(f ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+	runningTrackers.Add(1)
+	<-ctx.Done()
+	runningTrackers.Add(-1)
+	return nil
+}
+
+func (t *countingTracker) Ping([]model.Device) {}
+
+func init() {
+	Register("counting", func(config.Settings) (Tracker, error) { return &countingTracker{}, nil },
+		config.Schema{{Name: "x", Type: config.TypeInt}})
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	os.WriteFile(filename, []byte("trackers:\n  counting/a: {x: '1'}\n  counting/b: {}\n"), 0644)
+	registry := NewRegistry(config.Retrieve(dir, dir))
+	ctx, cancel := context.WithCancel(context.Background())
+	registry.Start(ctx)
+	defer func() {
+		cancel()
+		registry.Stop()
+	}()
+	assert.Eventually(t, func() bool { return runningTrackers.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Nil(t, registry.AddDevice(model.Device{Identifier: "phone", Status: model.StatusTracked,
+		Interfaces: []model.Interface{{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}}}))
+	registry.reportPresence([]model.DetectedInterface{{Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff"}}})
+
+	os.WriteFile(filename, []byte("trackers:\n  counting/a: {x: '2'}\n  counting/c: {}\nstorage:\n  backend: bolt\n"), 0644)
+	_, report, err := registry.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"counting/c"}, report.Started)
+	assert.Equal(t, []string{"counting/b"}, report.Stopped)
+	assert.Equal(t, []string{"counting/a"}, report.Restarted)
+	assert.Equal(t, []string{"storage"}, report.RestartRequired)
+	assert.Eventually(t, func() bool { return runningTrackers.Load() == 2 }, time.Second, time.Millisecond)
+
+	// the presence state is kept
+	d, _ := registry.FindDevice("phone")
+	assert.True(t, d.Present)
+
+	// nothing changes when the configuration is invalid
+	os.WriteFile(filename, []byte("trackers:\n  unknown: {}\n"), 0644)
+	_, _, err = registry.Reload()
+	var problems config.Problems
+	assert.ErrorAs(t, err, &problems)
+	assert.Equal(t, 2, len(registry.watchdog.trackers))
+	assert.Equal(t, "2", registry.watchdog.settings["counting/a"]["x"])
+}
@@ -0,0 +1,105 @@
+package device
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"net"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// maxResolvedAddresses bounds the cache of resolved addresses (as they keep rotating).
+const maxResolvedAddresses = 4096
+
+// parseIRK decodes an Identity Resolving Key given as 32 hexadecimal digits
+// (optionally separated by colons).
+func parseIRK(v string) ([]byte, error) {
+	irk, err := hex.DecodeString(strings.ReplaceAll(v, ":", ""))
+	if err != nil || len(irk) != 16 {
+		return nil, ErrInvalidIRK
+	}
+	return irk, nil
+}
+
+// isResolvablePrivateAddress tells whether a Bluetooth address looks like a resolvable
+// private one (i.e. the two most significant bits are 01).
+func isResolvablePrivateAddress(address string) bool {
+	hw, err := net.ParseMAC(address)
+	return err == nil && len(hw) == 6 && hw[0]>>6 == 0b01
+}
+
+// resolvesTo tells whether a resolvable private address was generated with the given IRK,
+// i.e. its hash (the 24 least significant bits) is ah(IRK, prand) (Core spec Vol 3, Part H, 2.2.2).
+func resolvesTo(irk []byte, address string) bool {
+	hw, err := net.ParseMAC(address)
+	if err != nil || len(hw) != 6 {
+		return false
+	}
+	return bytes.Equal(ah(irk, hw[:3]), hw[3:])
+}
+
+// ah is the random address hash function: the 24 least significant bits of
+// AES-128(k, padding || r), with r the 24 bits prand.
+func ah(k []byte, r []byte) []byte {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil
+	}
+	in := make([]byte, aes.BlockSize)
+	copy(in[aes.BlockSize-3:], r)
+	out := make([]byte, aes.BlockSize)
+	block.Encrypt(out, in)
+	return out[aes.BlockSize-3:]
+}
+
+// resolveDevice returns the device owning a resolvable private address, if any.
+func (r *Registry) resolveDevice(address string) *model.Device {
+	address = strings.ToUpper(address)
+	if id, found := r.resolved[address]; found {
+		return r.devices[id]
+	}
+	if len(r.resolved) >= maxResolvedAddresses {
+		clear(r.resolved)
+	}
+	r.resolved[address] = ""
+	for _, d := range r.devices {
+		for _, di := range d.Interfaces {
+			if di.Type != model.InterfaceBluetooth || len(di.IRK) == 0 {
+				continue
+			}
+			irk, err := parseIRK(di.IRK)
+			if err != nil {
+				continue
+			}
+			// tools do not agree on the byte order of the key, both are tried
+			if resolvesTo(irk, address) || resolvesTo(reversed(irk), address) {
+				r.resolved[address] = d.Identifier
+				log.Debugf("Resolved private address %s of: %s", address, d.Identifier)
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+func reversed(b []byte) []byte {
+	r := slices.Clone(b)
+	slices.Reverse(r)
+	return r
+}
+
+// validateIRKs checks the IRKs of the interfaces of a device.
+func validateIRKs(d model.Device) error {
+	for _, itf := range d.Interfaces {
+		if len(itf.IRK) > 0 {
+			if _, err := parseIRK(itf.IRK); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
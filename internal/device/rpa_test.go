@@ -0,0 +1,79 @@
+package device
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// the sample data of the Bluetooth Core specification (Vol 3, Part H, D.7)
+const sampleIRK = "ec0234a357c8ad05341010a60a397d9b"
+
+const sampleAddress = "70:81:94:0d:fb:aa"
+
+func TestAh(t *testing.T) {
+	irk, err := parseIRK(sampleIRK)
+	assert.Nil(t, err)
+	assert.Equal(t, "0dfbaa", hex.EncodeToString(ah(irk, []byte{0x70, 0x81, 0x94})))
+}
+
+func TestParseIRK(t *testing.T) {
+	_, err := parseIRK("EC:02:34:A3:57:C8:AD:05:34:10:10:A6:0A:39:7D:9B")
+	assert.Nil(t, err)
+	_, err = parseIRK("ec0234")
+	assert.Equal(t, ErrInvalidIRK, err)
+	_, err = parseIRK("not an irk at all, not an irk at")
+	assert.Equal(t, ErrInvalidIRK, err)
+}
+
+func TestResolvesTo(t *testing.T) {
+	irk, _ := parseIRK(sampleIRK)
+	assert.True(t, resolvesTo(irk, sampleAddress))
+	assert.True(t, resolvesTo(irk, "70:81:94:0D:FB:AA"))
+	assert.False(t, resolvesTo(irk, "70:81:94:0d:fb:ab"))
+	assert.False(t, resolvesTo(irk, "6b:a3:f9:22:7e:a1-c0c4-11eb"))
+}
+
+func TestIsResolvablePrivateAddress(t *testing.T) {
+	assert.True(t, isResolvablePrivateAddress(sampleAddress))
+	assert.False(t, isResolvablePrivateAddress("c0:81:94:0d:fb:aa"))
+	assert.False(t, isResolvablePrivateAddress("30:81:94:0d:fb:aa"))
+	assert.False(t, isResolvablePrivateAddress("6ba3f922-7ea1-c0c4-11eb-000000000000"))
+}
+
+func TestReportPresenceOfAPrivateAddress(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{
+		"phone": {Identifier: "phone", Status: model.StatusTracked, Interfaces: []model.Interface{
+			{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:ff", IRK: sampleIRK},
+		}},
+	}})
+
+	itf := model.Interface{Type: model.InterfaceBluetooth, MACAddress: "70:81:94:0D:FB:AA"}
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+	d, _ := registry.FindDevice("phone")
+	assert.True(t, d.Present)
+	assert.Equal(t, 1, len(registry.GetDevices(model.StatusUndefined)))
+	assert.Equal(t, "phone", registry.resolved["70:81:94:0D:FB:AA"])
+
+	// the key may be given least significant octet first
+	registry.devices["phone"].Interfaces[0].IRK = "9b7d390aa610103405adc857a33402ec"
+	clear(registry.resolved)
+	assert.NotNil(t, registry.lookupDevice(itf))
+
+	// an address not resolving to a known device gets discovered
+	itf.MACAddress = "70:81:94:0d:fb:ab"
+	registry.reportPresence([]model.DetectedInterface{{Interface: itf}})
+	assert.Equal(t, 2, len(registry.GetDevices(model.StatusUndefined)))
+	assert.Equal(t, "", registry.resolved["70:81:94:0D:FB:AB"])
+}
+
+func TestAddDeviceWithInvalidIRK(t *testing.T) {
+	registry := NewRegistry(config.Config{Devices: map[string]*model.Device{}})
+	err := registry.AddDevice(model.Device{Identifier: "phone", Status: model.StatusTracked, Interfaces: []model.Interface{
+		{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:ff", IRK: "1234"},
+	}})
+	assert.Equal(t, ErrInvalidIRK, err)
+}
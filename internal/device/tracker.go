@@ -2,9 +2,11 @@ package device
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/touchardv/myhome-presence/internal/config"
 	"github.com/touchardv/myhome-presence/pkg/model"
 )
@@ -16,8 +18,30 @@ type ReportPresenceFunc func([]model.DetectedInterface)
 const (
 	ReportDataSuggestedIdentifier  = "Identifier"
 	ReportDataSuggestedDescription = "Description"
+
+	// ReportDataLatency is the measured round-trip time (as a duration, e.g. "12.5ms").
+	ReportDataLatency = "Latency"
+	// ReportDataPacketLoss is the ratio of lost packets (between 0 and 1).
+	ReportDataPacketLoss = "PacketLoss"
+	// ReportDataRSSI is the received signal strength (in dBm).
+	ReportDataRSSI = "RSSI"
+	// ReportDataTxPower is the advertised transmit power (in dBm).
+	ReportDataTxPower = "TxPower"
+	// ReportDataDistance is the estimated distance (in meters).
+	ReportDataDistance = "Distance"
+	// ReportDataGracePeriod is, for a departure, how long the (passive) sightings
+	// of the device are then ignored (as a duration, e.g. "10m").
+	ReportDataGracePeriod = "GracePeriod"
 )
 
+// SetData sets the (trimmed) value of a report data entry, unless empty.
+func SetData(data map[string]string, key string, value string) {
+	value = strings.TrimSpace(value)
+	if len(value) > 0 {
+		data[key] = value
+	}
+}
+
 // Tracker tracks the presence of devices.
 type Tracker interface {
 	Loop(deviceReport ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error
@@ -25,20 +49,47 @@ type Tracker interface {
 	Ping([]model.Device)
 }
 
-// NewTrackerFunc is a factory function for instantiating a new Tracker.
-type NewTrackerFunc func(config.Settings) Tracker
+// HTTPTracker is a Tracker receiving sightings from HTTP requests (e.g. sent by phones).
+// It handles the requests sent to /api/<tracker name>/...
+type HTTPTracker interface {
+	Tracker
+	http.Handler
+}
+
+// NewTrackerFunc is a factory function for instantiating a new Tracker,
+// failing when its settings are invalid.
+type NewTrackerFunc func(config.Settings) (Tracker, error)
 
 var factories map[string]NewTrackerFunc = make(map[string]NewTrackerFunc)
 
-// Register records a Tracker factory function by name.
-func Register(name string, f NewTrackerFunc) {
+// Register records a Tracker factory function by name, together with the schema
+// of its settings (used for validating the configuration).
+func Register(name string, f NewTrackerFunc, schema config.Schema) {
 	factories[name] = f
+	config.RegisterSchema(name, schema, func(settings config.Settings) error {
+		_, err := f(settings)
+		return err
+	})
+}
+
+// trackerRegistered tells whether a tracker configuration name matches a registered tracker.
+func trackerRegistered(name string) bool {
+	factory, _, _ := strings.Cut(name, "/")
+	_, found := factories[factory]
+	return found
 }
 
-func newTracker(name string, settings config.Settings) Tracker {
-	if f, ok := factories[name]; ok {
-		return f(settings)
+// newTracker instantiates a Tracker given its configuration name.
+// A name like "<tracker>/<instance>" allows configuring several instances of the same tracker.
+func newTracker(name string, settings config.Settings) (Tracker, error) {
+	factory, _, _ := strings.Cut(name, "/")
+	f, ok := factories[factory]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchTracker, name)
+	}
+	t, err := f(settings)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", name, err)
 	}
-	log.Fatal("No such tracker: ", name)
-	return nil
+	return t, nil
 }
@@ -14,8 +14,8 @@ type dummyTracker struct {
 
 var tracker dummyTracker
 
-func newDummyTracker(config.Settings) Tracker {
-	return &tracker
+func newDummyTracker(config.Settings) (Tracker, error) {
+	return &tracker, nil
 }
 
 func (t *dummyTracker) Loop(f ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
@@ -29,5 +29,5 @@ func (t *dummyTracker) Ping(device []model.Device) {
 }
 
 func init() {
-	Register("dummy", newDummyTracker)
+	Register("dummy", newDummyTracker, config.Schema{})
 }
@@ -2,6 +2,9 @@ package device
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -13,29 +16,53 @@ import (
 type watchdog struct {
 	stopped  chan bool
 	stopping chan interface{}
-	trackers []Tracker
+	mutex    sync.RWMutex
+	trackers map[string]Tracker
+	settings map[string]config.Settings
+	running  map[string]*runningTracker
+
+	// ctx and registry are set once the loop runs, for (re)starting trackers.
+	ctx      context.Context
+	registry *Registry
+}
+
+// runningTracker allows stopping a single tracker, e.g. when its settings changed.
+type runningTracker struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 func newWatchDog(cfg config.Config) *watchdog {
-	trackers := make([]Tracker, 0)
-	for name, settings := range cfg.Trackers {
-		trackers = append(trackers, newTracker(name, settings))
+	trackers := make(map[string]Tracker)
+	settings := make(map[string]config.Settings)
+	for name, s := range cfg.Trackers {
+		t, err := newTracker(name, s)
+		if err != nil {
+			// the configuration is validated beforehand
+			log.Error("Failed to create the tracker: ", err)
+			continue
+		}
+		trackers[name] = t
+		settings[name] = s
 	}
 	return &watchdog{
 		stopped:  make(chan bool),
 		stopping: make(chan interface{}),
 		trackers: trackers,
+		settings: settings,
+		running:  make(map[string]*runningTracker),
 	}
 }
 
 func (w *watchdog) loop(r *Registry, ctx context.Context) {
 	log.Info("Starting: device watchdog")
-	var trackersWg sync.WaitGroup
-
-	trackersWg.Add(len(w.trackers))
-	for _, t := range w.trackers {
-		go t.Loop(r.reportPresence, ctx, &trackersWg)
+	w.mutex.Lock()
+	w.ctx = ctx
+	w.registry = r
+	for name, t := range w.trackers {
+		w.start(name, t)
 	}
+	w.mutex.Unlock()
 
 	needUpdate := false
 	check := time.NewTimer(5 * time.Second)
@@ -43,7 +70,11 @@ func (w *watchdog) loop(r *Registry, ctx context.Context) {
 		select {
 		case <-w.stopping:
 			log.Info("Stopping: trackers...")
-			trackersWg.Wait()
+			w.mutex.Lock()
+			for _, rt := range w.running {
+				rt.wg.Wait()
+			}
+			w.mutex.Unlock()
 			log.Info("Stopped: trackers")
 			w.stopped <- true
 			return
@@ -61,6 +92,84 @@ func (w *watchdog) loop(r *Registry, ctx context.Context) {
 	}
 }
 
+// start runs a tracker loop (the mutex being locked).
+func (w *watchdog) start(name string, t Tracker) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	rt := &runningTracker{cancel: cancel}
+	rt.wg.Add(1)
+	w.running[name] = rt
+	go t.Loop(w.registry.reporter(name), ctx, &rt.wg)
+}
+
+// reconfigure stops the trackers that were removed or whose settings changed,
+// then starts the new ones; the other trackers keep running.
+func (w *watchdog) reconfigure(trackers map[string]config.Settings) (ReloadReport, error) {
+	report := ReloadReport{Started: []string{}, Stopped: []string{}, Restarted: []string{}}
+
+	// creating the new trackers first, for changing nothing when some settings are invalid
+	w.mutex.RLock()
+	created := make(map[string]Tracker)
+	errs := []error{}
+	for name, settings := range trackers {
+		if current, found := w.settings[name]; found && maps.Equal(settings, current) {
+			continue
+		}
+		t, err := newTracker(name, settings)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		created[name] = t
+	}
+	w.mutex.RUnlock()
+	if err := errors.Join(errs...); err != nil {
+		return report, err
+	}
+
+	w.mutex.Lock()
+	stopping := []*runningTracker{}
+	for name := range w.trackers {
+		settings, found := trackers[name]
+		if found && maps.Equal(settings, w.settings[name]) {
+			continue
+		}
+		if found {
+			report.Restarted = append(report.Restarted, name)
+		} else {
+			report.Stopped = append(report.Stopped, name)
+		}
+		delete(w.trackers, name)
+		delete(w.settings, name)
+		if rt, running := w.running[name]; running {
+			stopping = append(stopping, rt)
+			delete(w.running, name)
+		}
+	}
+	w.mutex.Unlock()
+
+	// not holding the mutex: the trackers may be reporting while stopping
+	for _, rt := range stopping {
+		rt.cancel()
+		rt.wg.Wait()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for name, t := range created {
+		if !slices.Contains(report.Restarted, name) {
+			report.Started = append(report.Started, name)
+		}
+		w.trackers[name] = t
+		w.settings[name] = trackers[name]
+		if w.ctx != nil {
+			w.start(name, t)
+		}
+	}
+	slices.Sort(report.Started)
+	slices.Sort(report.Stopped)
+	slices.Sort(report.Restarted)
+	return report, nil
+}
+
 func (w *watchdog) stop() {
 	log.Info("Stopping: device watchdog...")
 	close(w.stopping)
@@ -85,7 +194,11 @@ func (w *watchdog) pingMissingDevices(r *Registry, now time.Time) {
 }
 
 func (w *watchdog) ping(devices []model.Device) {
-	for _, t := range w.trackers {
+	// the trackers ping synchronously: not holding the lock meanwhile (e.g. while reloading)
+	w.mutex.RLock()
+	trackers := slices.Collect(maps.Values(w.trackers))
+	w.mutex.RUnlock()
+	for _, t := range trackers {
 		t.Ping(devices)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
 	"github.com/touchardv/myhome-presence/pkg/model"
 )
 
@@ -48,3 +49,15 @@ func TestPingMissingDevices(t *testing.T) {
 	assert.Equal(t, 2, tracker.pingCount)
 	assert.False(t, device.Present)
 }
+
+func TestNewTrackerInstances(t *testing.T) {
+	tr, err := newTracker("dummy", config.Settings{})
+	assert.Nil(t, err)
+	assert.Same(t, &tracker, tr)
+	tr, err = newTracker("dummy/second", config.Settings{})
+	assert.Nil(t, err)
+	assert.Same(t, &tracker, tr)
+
+	_, err = newTracker("unknown", config.Settings{})
+	assert.ErrorIs(t, err, ErrNoSuchTracker)
+}
@@ -0,0 +1,226 @@
+// Package jsonpath evaluates a subset of JSONPath expressions against
+// decoded JSON documents (as produced by encoding/json).
+//
+// Supported: the root ($ or @, optional), child members (.name, ['name']),
+// array indexes ([0], [-1]), wildcards (.*, [*]) and recursive descent (..name).
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	child stepKind = iota
+	index
+	wildcard
+	descendant
+)
+
+type step struct {
+	kind  stepKind
+	name  string
+	index int
+}
+
+// Path is a compiled JSONPath expression.
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// Compile parses a JSONPath expression.
+func Compile(expr string) (Path, error) {
+	p := Path{expr: expr}
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "$") || strings.HasPrefix(s, "@") {
+		s = s[1:]
+	}
+	if len(s) > 0 && s[0] != '.' && s[0] != '[' {
+		// relative expression, e.g. "data.hosts"
+		s = "." + s
+	}
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := member(s[2:])
+			if len(name) == 0 {
+				return p, fmt.Errorf("invalid expression %q: missing member name after '..'", expr)
+			}
+			p.steps = append(p.steps, step{kind: descendant, name: name})
+			s = rest
+
+		case s[0] == '.':
+			name, rest := member(s[1:])
+			if len(name) == 0 {
+				return p, fmt.Errorf("invalid expression %q: missing member name after '.'", expr)
+			}
+			if name == "*" {
+				p.steps = append(p.steps, step{kind: wildcard})
+			} else {
+				p.steps = append(p.steps, step{kind: child, name: name})
+			}
+			s = rest
+
+		case s[0] == '[':
+			st, rest, err := bracket(s[1:])
+			if err != nil {
+				return p, fmt.Errorf("invalid expression %q: %w", expr, err)
+			}
+			p.steps = append(p.steps, st)
+			s = rest
+
+		default:
+			return p, fmt.Errorf("invalid expression %q: unexpected character %q", expr, s[0])
+		}
+	}
+	return p, nil
+}
+
+// MustCompile is like Compile but panics if the expression is invalid.
+func MustCompile(expr string) Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func member(s string) (string, string) {
+	end := strings.IndexAny(s, ".[]")
+	if end < 0 {
+		end = len(s)
+	}
+	return s[:end], s[end:]
+}
+
+func bracket(s string) (step, string, error) {
+	if len(s) > 0 && (s[0] == '\'' || s[0] == '"') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 || !strings.HasPrefix(s[end+2:], "]") {
+			return step{}, s, fmt.Errorf("unterminated member name")
+		}
+		return step{kind: child, name: s[1 : end+1]}, s[end+3:], nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return step{}, s, fmt.Errorf("missing ']'")
+	}
+	content := strings.TrimSpace(s[:end])
+	if content == "*" {
+		return step{kind: wildcard}, s[end+1:], nil
+	}
+	i, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, s, fmt.Errorf("unsupported selector [%s]", content)
+	}
+	return step{kind: index, index: i}, s[end+1:], nil
+}
+
+// String returns the source expression.
+func (p Path) String() string {
+	return p.expr
+}
+
+// Eval returns the values matching the expression, in document order
+// (object members are visited in key order).
+func (p Path) Eval(v interface{}) []interface{} {
+	nodes := []interface{}{v}
+	for _, st := range p.steps {
+		next := []interface{}{}
+		for _, n := range nodes {
+			next = st.apply(n, next)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (st step) apply(n interface{}, out []interface{}) []interface{} {
+	switch st.kind {
+	case child:
+		if m, ok := n.(map[string]interface{}); ok {
+			if c, found := m[st.name]; found {
+				out = append(out, c)
+			}
+		}
+
+	case index:
+		if a, ok := n.([]interface{}); ok {
+			i := st.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				out = append(out, a[i])
+			}
+		}
+
+	case wildcard:
+		out = append(out, children(n)...)
+
+	case descendant:
+		// pre-order traversal of the node and all its descendants
+		if m, ok := n.(map[string]interface{}); ok && st.name != "*" {
+			if c, found := m[st.name]; found {
+				out = append(out, c)
+			}
+		}
+		for _, c := range children(n) {
+			if st.name == "*" {
+				out = append(out, c)
+			}
+			out = st.apply(c, out)
+		}
+	}
+	return out
+}
+
+func children(n interface{}) []interface{} {
+	switch v := n.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = v[k]
+		}
+		return values
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// First evaluates the expression and returns the first matching scalar
+// value formatted as a string, or an empty string when nothing matches.
+func (p Path) First(v interface{}) string {
+	values := p.Eval(v)
+	if len(values) == 0 {
+		return ""
+	}
+	switch s := values[0].(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(s)
+	default:
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+}
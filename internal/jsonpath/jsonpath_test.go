@@ -0,0 +1,79 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{
+	"data": {
+		"hosts": [
+			{"mac": "AA:BB:CC:DD:EE:01", "ip": "192.10.20.30", "info": {"name": "phone", "active": true}},
+			{"mac": "AA:BB:CC:DD:EE:02", "ip": "192.10.20.31", "info": {"name": "nas", "active": false}},
+			{"mac": "AA:BB:CC:DD:EE:03", "link rate": 866}
+		],
+		"token": "abc"
+	}
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	assert.Nil(t, d.Decode(&v))
+	return v
+}
+
+func TestEval(t *testing.T) {
+	doc := decode(t, document)
+
+	tests := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{"$.data.token", []interface{}{"abc"}},
+		{"data.token", []interface{}{"abc"}},
+		{"$['data'][\"token\"]", []interface{}{"abc"}},
+		{"$.data.hosts[0].mac", []interface{}{"AA:BB:CC:DD:EE:01"}},
+		{"$.data.hosts[-1].mac", []interface{}{"AA:BB:CC:DD:EE:03"}},
+		{"$.data.hosts[3].mac", []interface{}{}},
+		{"$.data.hosts[*].ip", []interface{}{"192.10.20.30", "192.10.20.31"}},
+		{"$.data.hosts[2]['link rate']", []interface{}{json.Number("866")}},
+		{"$..name", []interface{}{"phone", "nas"}},
+		{"$.data.missing", []interface{}{}},
+		{"$.data.token.length", []interface{}{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, MustCompile(test.expr).Eval(doc), test.expr)
+	}
+
+	assert.Equal(t, 3, len(MustCompile("$.data.hosts.*").Eval(doc)))
+	assert.Equal(t, 1, len(MustCompile("$").Eval(doc)))
+	assert.Equal(t, 2, len(MustCompile("@.data.*").Eval(doc)))
+}
+
+func TestFirst(t *testing.T) {
+	host := decode(t, `{"name": "nas", "active": false, "port": 3, "rate": 1.5, "ip": null, "tags": ["a"]}`)
+
+	assert.Equal(t, "nas", MustCompile("name").First(host))
+	assert.Equal(t, "false", MustCompile("active").First(host))
+	assert.Equal(t, "3", MustCompile("port").First(host))
+	assert.Equal(t, "1.5", MustCompile("rate").First(host))
+	assert.Equal(t, "", MustCompile("ip").First(host))
+	assert.Equal(t, "", MustCompile("missing").First(host))
+	assert.Equal(t, `["a"]`, MustCompile("tags").First(host))
+
+	var v interface{}
+	json.Unmarshal([]byte(`{"port": 3}`), &v)
+	assert.Equal(t, "3", MustCompile("port").First(v))
+}
+
+func TestCompileInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"$.", "$..", "$[", "$[abc]", "$['abc]", "$.a]"} {
+		_, err := Compile(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
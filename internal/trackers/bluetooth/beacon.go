@@ -0,0 +1,162 @@
+package bluetooth
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const (
+	reportDataBeaconType   = "BeaconType"
+	reportDataManufacturer = "Manufacturer"
+	// reportDataMeasuredPower is the calibrated RSSI (in dBm) at 1 meter, as advertised by beacons.
+	reportDataMeasuredPower = "MeasuredPower"
+)
+
+const (
+	companyApple  = 0x004c
+	iBeaconType   = 0x02
+	iBeaconLength = 0x15
+	altBeaconCode = 0xbeac
+)
+
+const (
+	eddystoneUUID = "0000feaa-0000-1000-8000-00805f9b34fb"
+	eddystoneUID  = 0x00
+	eddystoneURL  = 0x10
+	eddystoneTLM  = 0x20
+)
+
+// companies are some common Bluetooth SIG company identifiers.
+var companies = map[uint16]string{
+	0x0006: "Microsoft",
+	0x000d: "Texas Instruments",
+	0x004c: "Apple",
+	0x0059: "Nordic Semiconductor",
+	0x0075: "Samsung",
+	0x0087: "Garmin",
+	0x00e0: "Google",
+	0x0118: "Radius Networks",
+}
+
+// appleTypes are the types of the Apple (Continuity) advertisements.
+var appleTypes = map[byte]string{
+	0x02: "iBeacon",
+	0x05: "AirDrop",
+	0x07: "AirPods",
+	0x09: "AirPlay",
+	0x0c: "Handoff",
+	0x0f: "Nearby Action",
+	0x10: "Nearby Info",
+	0x12: "Find My",
+}
+
+// services are the 16 bits service UUIDs of some common key finders.
+var services = map[string]string{
+	"0000feec-0000-1000-8000-00805f9b34fb": "Tile",
+	"0000feed-0000-1000-8000-00805f9b34fb": "Tile",
+	"0000fd5a-0000-1000-8000-00805f9b34fb": "Samsung SmartTag",
+}
+
+var eddystoneSchemes = []string{"http://www.", "https://www.", "http://", "https://"}
+
+var eddystoneExpansions = []string{
+	".com/", ".org/", ".edu/", ".net/", ".info/", ".biz/", ".gov/",
+	".com", ".org", ".edu", ".net", ".info", ".biz", ".gov",
+}
+
+// decodeManufacturerData decodes the manufacturer specific data of an advertisement
+// (iBeacon, AltBeacon or else the manufacturer and the raw data).
+func decodeManufacturerData(company uint16, b []byte, itf *model.DetectedInterface) {
+	itf.Data[fmt.Sprintf("ManufacturerData-%04x", company)] = hex.EncodeToString(b)
+	if name, found := companies[company]; found {
+		itf.Data[reportDataManufacturer] = name
+	}
+
+	switch {
+	case company == companyApple && len(b) >= 23 && b[0] == iBeaconType && b[1] == iBeaconLength:
+		itf.BeaconID = beaconID("ibeacon", b[2:22])
+		itf.Data[reportDataBeaconType] = "iBeacon"
+		itf.Data[reportDataMeasuredPower] = strconv.Itoa(int(int8(b[22])))
+
+	case len(b) >= 24 && binary.BigEndian.Uint16(b) == altBeaconCode:
+		itf.BeaconID = beaconID("altbeacon", b[2:22])
+		itf.Data[reportDataBeaconType] = "AltBeacon"
+		itf.Data[reportDataMeasuredPower] = strconv.Itoa(int(int8(b[22])))
+
+	case company == companyApple && len(b) > 0:
+		if t, found := appleTypes[b[0]]; found {
+			itf.Data[reportDataBeaconType] = "Apple " + t
+		}
+	}
+}
+
+// decodeServiceData decodes the service data of an advertisement (Eddystone
+// frames, or else the raw data).
+func decodeServiceData(uuid string, b []byte, itf *model.DetectedInterface) {
+	uuid = strings.ToLower(uuid)
+	itf.Data["ServiceData-"+uuid] = hex.EncodeToString(b)
+	if name, found := services[uuid]; found {
+		itf.Data[reportDataBeaconType] = name
+	}
+	if uuid != eddystoneUUID || len(b) < 2 {
+		return
+	}
+
+	switch b[0] {
+	case eddystoneUID:
+		if len(b) < 18 {
+			return
+		}
+		itf.BeaconID = "eddystone:" + hex.EncodeToString(b[2:12]) + ":" + hex.EncodeToString(b[12:18])
+		itf.Data[reportDataBeaconType] = "Eddystone-UID"
+		itf.Data[device.ReportDataTxPower] = strconv.Itoa(int(int8(b[1])))
+
+	case eddystoneURL:
+		if len(b) < 3 || int(b[2]) >= len(eddystoneSchemes) {
+			return
+		}
+		itf.Data[reportDataBeaconType] = "Eddystone-URL"
+		itf.Data[device.ReportDataTxPower] = strconv.Itoa(int(int8(b[1])))
+		itf.Data["URL"] = eddystoneSchemes[b[2]] + expandURL(b[3:])
+
+	case eddystoneTLM:
+		// only the unencrypted version is supported
+		if len(b) < 14 || b[1] != 0 {
+			return
+		}
+		if _, found := itf.Data[reportDataBeaconType]; !found {
+			itf.Data[reportDataBeaconType] = "Eddystone-TLM"
+		}
+		if v := binary.BigEndian.Uint16(b[2:]); v > 0 {
+			itf.Data["BatteryVoltage"] = strconv.Itoa(int(v))
+		}
+		if v := int16(binary.BigEndian.Uint16(b[4:])); v != -0x8000 {
+			itf.Data["Temperature"] = strconv.FormatFloat(float64(v)/256, 'f', 1, 64)
+		}
+	}
+}
+
+// beaconID formats a 20 bytes beacon identifier as "<type>:<uuid>:<major>:<minor>".
+func beaconID(kind string, b []byte) string {
+	u := hex.EncodeToString(b[:16])
+	return fmt.Sprintf("%s:%s-%s-%s-%s-%s:%d:%d", kind, u[:8], u[8:12], u[12:16], u[16:20], u[20:],
+		binary.BigEndian.Uint16(b[16:]), binary.BigEndian.Uint16(b[18:]))
+}
+
+func expandURL(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if int(c) < len(eddystoneExpansions) {
+			sb.WriteString(eddystoneExpansions[c])
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package bluetooth
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func newSighting() *model.DetectedInterface {
+	return &model.DetectedInterface{
+		Interface: model.Interface{Type: model.InterfaceBluetooth, MACAddress: "5A:BB:CC:DD:EE:01"},
+		Data:      map[string]string{},
+	}
+}
+
+func decodeHex(s string) []byte {
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+func TestDecodeIBeacon(t *testing.T) {
+	itf := newSighting()
+	decodeManufacturerData(0x004c, decodeHex("0215f7826da64fa24e988024bc5b71e0893e00640001c5"), itf)
+	assert.Equal(t, "ibeacon:f7826da6-4fa2-4e98-8024-bc5b71e0893e:100:1", itf.BeaconID)
+	assert.Equal(t, "iBeacon", itf.Data["BeaconType"])
+	assert.Equal(t, "Apple", itf.Data["Manufacturer"])
+	assert.Equal(t, "-59", itf.Data["MeasuredPower"])
+	assert.Equal(t, "0215f7826da64fa24e988024bc5b71e0893e00640001c5", itf.Data["ManufacturerData-004c"])
+}
+
+func TestDecodeAltBeacon(t *testing.T) {
+	itf := newSighting()
+	decodeManufacturerData(0x0118, decodeHex("beac2f234454cf6d4a0fadf2f4911ba9ffa600010002c500"), itf)
+	assert.Equal(t, "altbeacon:2f234454-cf6d-4a0f-adf2-f4911ba9ffa6:1:2", itf.BeaconID)
+	assert.Equal(t, "AltBeacon", itf.Data["BeaconType"])
+	assert.Equal(t, "Radius Networks", itf.Data["Manufacturer"])
+	assert.Equal(t, "-59", itf.Data["MeasuredPower"])
+}
+
+func TestDecodeAppleAdvertisement(t *testing.T) {
+	itf := newSighting()
+	decodeManufacturerData(0x004c, decodeHex("12190010"), itf)
+	assert.Empty(t, itf.BeaconID)
+	assert.Equal(t, "Apple Find My", itf.Data["BeaconType"])
+
+	itf = newSighting()
+	decodeManufacturerData(0x1234, decodeHex("0102"), itf)
+	assert.Empty(t, itf.BeaconID)
+	assert.Equal(t, "0102", itf.Data["ManufacturerData-1234"])
+	assert.NotContains(t, itf.Data, "Manufacturer")
+}
+
+func TestDecodeEddystoneUID(t *testing.T) {
+	itf := newSighting()
+	decodeServiceData(eddystoneUUID, decodeHex("00ee8b0c2ab3e06d3ad5b2d00102030405060000"), itf)
+	assert.Equal(t, "eddystone:8b0c2ab3e06d3ad5b2d0:010203040506", itf.BeaconID)
+	assert.Equal(t, "Eddystone-UID", itf.Data["BeaconType"])
+	assert.Equal(t, "-18", itf.Data[device.ReportDataTxPower])
+}
+
+func TestDecodeEddystoneURL(t *testing.T) {
+	itf := newSighting()
+	decodeServiceData("0000FEAA-0000-1000-8000-00805F9B34FB", decodeHex("10ee03676f6f676c6500"), itf)
+	assert.Empty(t, itf.BeaconID)
+	assert.Equal(t, "Eddystone-URL", itf.Data["BeaconType"])
+	assert.Equal(t, "https://google.com/", itf.Data["URL"])
+}
+
+func TestDecodeEddystoneTLM(t *testing.T) {
+	itf := newSighting()
+	decodeServiceData(eddystoneUUID, decodeHex("20000bb81680000000010000000a"), itf)
+	assert.Equal(t, "Eddystone-TLM", itf.Data["BeaconType"])
+	assert.Equal(t, "3000", itf.Data["BatteryVoltage"])
+	assert.Equal(t, "22.5", itf.Data["Temperature"])
+
+	// truncated frames are ignored
+	itf = newSighting()
+	decodeServiceData(eddystoneUUID, decodeHex("2000"), itf)
+	assert.NotContains(t, itf.Data, "BeaconType")
+}
+
+func TestDecodeKeyFinderServiceData(t *testing.T) {
+	itf := newSighting()
+	decodeServiceData("0000feed-0000-1000-8000-00805f9b34fb", decodeHex("0200"), itf)
+	assert.Equal(t, "Tile", itf.Data["BeaconType"])
+	assert.Equal(t, "0200", itf.Data["ServiceData-0000feed-0000-1000-8000-00805f9b34fb"])
+}
@@ -1,6 +1,87 @@
 package bluetooth
 
-import "github.com/touchardv/myhome-presence/pkg/model"
+import (
+	"strconv"
+	"strings"
+	"time"
 
-func (t *btTracker) Ping(d []model.Device) {
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const defaultPingTimeout = 5 * time.Second
+
+const defaultPingConcurrency = 2
+
+// prober checks that a Bluetooth device is reachable, by connecting to it.
+type prober interface {
+	probe(address string, addressType string, timeout time.Duration) error
+}
+
+func (t *btTracker) Ping(devices []model.Device) {
+	t.mutex.Lock()
+	report := t.report
+	t.mutex.Unlock()
+	if t.prober == nil || report == nil {
+		return
+	}
+
+	for _, d := range devices {
+		addressType := d.Properties["AddressType"]
+		for _, itf := range d.Interfaces {
+			if itf.Type != model.InterfaceBluetooth || len(itf.MACAddress) == 0 {
+				continue
+			}
+			if addressType == "random" && isResolvable(itf.MACAddress) {
+				// the address has most likely changed since it was seen
+				continue
+			}
+			if !t.startProbing(itf.MACAddress) {
+				continue
+			}
+			go func(itf model.Interface) {
+				defer t.stopProbing(itf.MACAddress)
+				t.probes <- struct{}{}
+				defer func() { <-t.probes }()
+
+				err := t.prober.probe(itf.MACAddress, addressType, t.pingTimeout)
+				if err != nil {
+					log.Debugf("[bluetooth] %s did not answer: %s", itf.MACAddress, err)
+					return
+				}
+				log.Debugf("[bluetooth] %s answered", itf.MACAddress)
+				report([]model.DetectedInterface{{Interface: itf}})
+			}(itf)
+		}
+	}
+}
+
+// startProbing tells whether the address is not already being probed.
+func (t *btTracker) startProbing(address string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	address = strings.ToUpper(address)
+	if t.probing[address] {
+		return false
+	}
+	t.probing[address] = true
+	return true
+}
+
+func (t *btTracker) stopProbing(address string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.probing, strings.ToUpper(address))
+}
+
+// isResolvable tells whether a random address is a resolvable private one
+// (i.e. the two most significant bits are 01).
+func isResolvable(address string) bool {
+	if len(address) < 2 {
+		return false
+	}
+	b, err := strconv.ParseUint(address[:2], 16, 8)
+	return err == nil && b>>6 == 0b01
 }
@@ -0,0 +1,158 @@
+package bluetooth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// fakeBlueZ answers the probes of the addresses it knows about.
+type fakeBlueZ struct {
+	mutex     sync.Mutex
+	reachable map[string]bool
+	probed    []string
+	running   int
+	maxRun    int
+	release   chan struct{}
+}
+
+func (f *fakeBlueZ) probe(address string, addressType string, timeout time.Duration) error {
+	f.mutex.Lock()
+	f.probed = append(f.probed, address+"/"+addressType)
+	f.running++
+	f.maxRun = max(f.maxRun, f.running)
+	f.mutex.Unlock()
+
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.running--
+	if f.reachable[address] {
+		return nil
+	}
+	return errors.New("host is down")
+}
+
+func newTestTracker(f *fakeBlueZ, concurrency int) (*btTracker, *[]model.DetectedInterface, *sync.Mutex) {
+	mutex := &sync.Mutex{}
+	reported := []model.DetectedInterface{}
+	t := &btTracker{
+		prober:        f,
+		probing:       make(map[string]bool),
+		probes:        make(chan struct{}, concurrency),
+		pingTimeout:   time.Second,
+		readings:      make(map[string]rssiReading),
+		rssiSmoothing: defaultRSSISmoothing,
+	}
+	t.report = func(itfs []model.DetectedInterface) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reported = append(reported, itfs...)
+	}
+	return t, &reported, mutex
+}
+
+func btDevice(address string, addressType string) model.Device {
+	return model.Device{
+		Interfaces: []model.Interface{{Type: model.InterfaceBluetooth, MACAddress: address}},
+		Properties: map[string]string{"AddressType": addressType},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newBTTracker(config.Settings{})
+	assert.NoError(t, err)
+	tracker := tr.(*btTracker)
+	assert.Equal(t, defaultPingTimeout, tracker.pingTimeout)
+	assert.Equal(t, defaultPingConcurrency, cap(tracker.probes))
+
+	tr, err = newBTTracker(config.Settings{"ping_timeout": "2s", "ping_concurrency": "4"})
+	assert.NoError(t, err)
+	tracker = tr.(*btTracker)
+	assert.Equal(t, 2*time.Second, tracker.pingTimeout)
+	assert.Equal(t, 4, cap(tracker.probes))
+}
+
+func TestPing(t *testing.T) {
+	bluez := &fakeBlueZ{reachable: map[string]bool{"AA:BB:CC:DD:EE:01": true, "D0:BB:CC:DD:EE:03": true}}
+	tracker, reported, mutex := newTestTracker(bluez, 2)
+
+	tracker.Ping([]model.Device{
+		btDevice("AA:BB:CC:DD:EE:01", "public"),
+		btDevice("AA:BB:CC:DD:EE:02", "public"),
+		btDevice("D0:BB:CC:DD:EE:03", "random"), // static random address
+		btDevice("4A:BB:CC:DD:EE:04", "random"), // resolvable private address
+		{Interfaces: []model.Interface{{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:05"}}},
+	})
+
+	assert.Eventually(t, func() bool {
+		tracker.mutex.Lock()
+		defer tracker.mutex.Unlock()
+		return len(tracker.probing) == 0
+	}, time.Second, time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.ElementsMatch(t, []model.DetectedInterface{
+		{Interface: model.Interface{Type: model.InterfaceBluetooth, MACAddress: "AA:BB:CC:DD:EE:01"}},
+		{Interface: model.Interface{Type: model.InterfaceBluetooth, MACAddress: "D0:BB:CC:DD:EE:03"}},
+	}, *reported)
+	assert.ElementsMatch(t, []string{"AA:BB:CC:DD:EE:01/public", "AA:BB:CC:DD:EE:02/public", "D0:BB:CC:DD:EE:03/random"}, bluez.probed)
+}
+
+func TestPingConcurrency(t *testing.T) {
+	bluez := &fakeBlueZ{release: make(chan struct{})}
+	tracker, _, _ := newTestTracker(bluez, 2)
+
+	devices := []model.Device{
+		btDevice("AA:BB:CC:DD:EE:01", "public"),
+		btDevice("AA:BB:CC:DD:EE:02", "public"),
+		btDevice("AA:BB:CC:DD:EE:03", "public"),
+	}
+	tracker.Ping(devices)
+	// the same addresses are not probed twice at the same time
+	tracker.Ping(devices)
+
+	assert.Eventually(t, func() bool {
+		bluez.mutex.Lock()
+		defer bluez.mutex.Unlock()
+		return bluez.running == 2
+	}, time.Second, time.Millisecond)
+	for range devices {
+		bluez.release <- struct{}{}
+	}
+	assert.Eventually(t, func() bool {
+		tracker.mutex.Lock()
+		defer tracker.mutex.Unlock()
+		return len(tracker.probing) == 0
+	}, time.Second, time.Millisecond)
+
+	bluez.mutex.Lock()
+	defer bluez.mutex.Unlock()
+	assert.Equal(t, 3, len(bluez.probed))
+	assert.Equal(t, 2, bluez.maxRun)
+}
+
+func TestPingWhenNotStarted(t *testing.T) {
+	bluez := &fakeBlueZ{}
+	tracker, _, _ := newTestTracker(bluez, 1)
+	tracker.report = nil
+
+	tracker.Ping([]model.Device{btDevice("AA:BB:CC:DD:EE:01", "public")})
+	assert.Empty(t, bluez.probed)
+}
+
+func TestIsResolvable(t *testing.T) {
+	assert.True(t, isResolvable("4A:BB:CC:DD:EE:04"))
+	assert.True(t, isResolvable("7f:BB:CC:DD:EE:04"))
+	assert.False(t, isResolvable("D0:BB:CC:DD:EE:03"))
+	assert.False(t, isResolvable("0A:BB:CC:DD:EE:03"))
+}
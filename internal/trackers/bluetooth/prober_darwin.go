@@ -0,0 +1,7 @@
+package bluetooth
+
+// newProber returns no prober: CoreBluetooth does not expose the devices
+// addresses, hence they cannot be probed.
+func newProber() prober {
+	return nil
+}
@@ -0,0 +1,114 @@
+package bluetooth
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	psmSDP = 1 // the L2CAP channel of the service discovery protocol (classic)
+	cidATT = 4 // the L2CAP fixed channel of the attribute protocol (LE)
+)
+
+var errTimeout = errors.New("connection timeout")
+
+// l2capProber opens (and closes right away) an L2CAP connection to the
+// device: the SDP channel for a classic device, or the ATT channel (as a
+// GATT client would) for a Bluetooth Low Energy one.
+//
+// These are connection-oriented (SOCK_SEQPACKET) sockets of the kernel
+// Bluetooth stack, also used by BlueZ (e.g. by l2ping or gatttool): unlike raw
+// HCI sockets they need no capability (CAP_NET_RAW), and they do not bypass the
+// BlueZ adapter state. The BlueZ D-Bus Device1.Connect method is not used as it
+// only knows about the devices discovered recently, connects all their profiles
+// (e.g. audio) and keeps the connection up, rather than only checking whether
+// the device answers.
+type l2capProber struct{}
+
+func newProber() prober {
+	return &l2capProber{}
+}
+
+func (p *l2capProber) probe(address string, addressType string, timeout time.Duration) error {
+	hw, err := net.ParseMAC(address)
+	if err != nil || len(hw) != 6 {
+		return errors.New("invalid address: " + address)
+	}
+	var addr [6]uint8
+	copy(addr[:], hw)
+
+	if addressType == "random" {
+		return connect(&unix.SockaddrL2{CID: cidATT, Addr: addr, AddrType: unix.BDADDR_LE_RANDOM}, timeout)
+	}
+	// a public address is either a classic or a Bluetooth Low Energy one
+	err = connect(&unix.SockaddrL2{PSM: psmSDP, Addr: addr, AddrType: unix.BDADDR_BREDR}, timeout)
+	if err == nil {
+		return nil
+	}
+	if connect(&unix.SockaddrL2{CID: cidATT, Addr: addr, AddrType: unix.BDADDR_LE_PUBLIC}, timeout) == nil {
+		return nil
+	}
+	return err
+}
+
+func connect(sa *unix.SockaddrL2, timeout time.Duration) error {
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_SEQPACKET|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.BTPROTO_L2CAP)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer unix.Close(fd)
+
+	if sa.AddrType != unix.BDADDR_BREDR {
+		// the local address type must be LE as well
+		if err = unix.Bind(fd, &unix.SockaddrL2{CID: cidATT, AddrType: unix.BDADDR_LE_PUBLIC}); err != nil {
+			return os.NewSyscallError("bind", err)
+		}
+	}
+
+	err = unix.Connect(fd, sa)
+	if err == unix.EINPROGRESS {
+		err = waitConnected(fd, timeout)
+	}
+	if err == unix.ECONNREFUSED {
+		// the device answered
+		return nil
+	}
+	if err != nil {
+		return os.NewSyscallError("connect", err)
+	}
+	return nil
+}
+
+func waitConnected(fd int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		// a negative timeout would wait forever
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return errTimeout
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(fds, int(remaining.Milliseconds()))
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errTimeout
+		}
+		v, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return err
+		}
+		if v != 0 {
+			return unix.Errno(v)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package bluetooth
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const defaultRSSISmoothing = 0.3
+
+// defaultRSSIThreshold is the change (in dB) of the smoothed RSSI of a device
+// reported right away; smaller changes are reported once per report interval.
+const defaultRSSIThreshold = 5
+
+const defaultReportInterval = 1 * time.Minute
+
+// rssiMaxAge is the age after which a smoothed RSSI is discarded (e.g. the device went away).
+const rssiMaxAge = 2 * time.Minute
+
+// pathLossExponent is used for estimating the distance (2 in free space, higher indoors).
+const pathLossExponent = 2.5
+
+type rssiReading struct {
+	value     float64
+	updatedAt time.Time
+
+	// reported is the value last reported, at reportedAt.
+	reported   float64
+	reportedAt time.Time
+}
+
+// reportSightings smooths the RSSI of the sightings (with an exponentially weighted
+// moving average per address or beacon), then reports the ones that are strong enough,
+// unless reported recently with about the same RSSI (BlueZ signals every advertisement).
+func (t *btTracker) reportSightings(itfs []model.DetectedInterface) {
+	t.mutex.Lock()
+	report := t.report
+	now := time.Now()
+	kept := make([]model.DetectedInterface, 0, len(itfs))
+	for _, itf := range itfs {
+		raw, err := strconv.Atoi(itf.Data[device.ReportDataRSSI])
+		if err != nil {
+			kept = append(kept, itf)
+			continue
+		}
+		key := itf.MACAddress
+		if len(itf.BeaconID) > 0 {
+			// the address of beacons may rotate
+			key = itf.BeaconID
+		}
+		rssi := t.smooth(key, float64(raw), now)
+		if t.minRSSI != nil && rssi < float64(*t.minRSSI) {
+			continue
+		}
+		if t.throttled(key, rssi, now) {
+			continue
+		}
+		itf.Data[device.ReportDataRSSI] = strconv.Itoa(int(math.Round(rssi)))
+		if measuredPower, ok := measuredPower(itf.Data); ok {
+			itf.Data[device.ReportDataDistance] = strconv.FormatFloat(distance(rssi, measuredPower), 'f', 1, 64)
+		}
+		kept = append(kept, itf)
+	}
+	t.mutex.Unlock()
+
+	if report != nil && len(kept) > 0 {
+		report(kept)
+	}
+}
+
+func (t *btTracker) smooth(key string, rssi float64, now time.Time) float64 {
+	r, found := t.readings[key]
+	if found && now.Sub(r.updatedAt) < rssiMaxAge {
+		rssi = t.rssiSmoothing*rssi + (1-t.rssiSmoothing)*r.value
+	} else {
+		r = rssiReading{}
+	}
+	r.value, r.updatedAt = rssi, now
+	t.readings[key] = r
+	for k, r := range t.readings {
+		if now.Sub(r.updatedAt) >= rssiMaxAge {
+			delete(t.readings, k)
+		}
+	}
+	return rssi
+}
+
+// throttled tells whether a sighting is not to be reported, as it was already
+// reported less than the report interval ago with about the same RSSI.
+func (t *btTracker) throttled(key string, rssi float64, now time.Time) bool {
+	r := t.readings[key]
+	if !r.reportedAt.IsZero() && now.Sub(r.reportedAt) < t.reportInterval && math.Abs(rssi-r.reported) < float64(t.rssiThreshold) {
+		return true
+	}
+	r.reported, r.reportedAt = rssi, now
+	t.readings[key] = r
+	return false
+}
+
+// measuredPower returns the expected RSSI at 1 meter: either advertised (by beacons),
+// or computed from the TX power assuming the usual 41dB of loss at 1 meter.
+func measuredPower(data map[string]string) (int, bool) {
+	if v, err := strconv.Atoi(data[reportDataMeasuredPower]); err == nil {
+		return v, true
+	}
+	if v, err := strconv.Atoi(data[device.ReportDataTxPower]); err == nil {
+		return v - 41, true
+	}
+	return 0, false
+}
+
+// distance estimates the distance (in meters) given the RSSI and the expected RSSI at 1 meter.
+func distance(rssi float64, measuredPower int) float64 {
+	return math.Pow(10, (float64(measuredPower)-rssi)/(10*pathLossExponent))
+}
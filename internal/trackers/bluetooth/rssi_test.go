@@ -0,0 +1,98 @@
+package bluetooth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func sighting(address string, rssi string) model.DetectedInterface {
+	return model.DetectedInterface{
+		Interface: model.Interface{Type: model.InterfaceBluetooth, MACAddress: address},
+		Data:      map[string]string{device.ReportDataRSSI: rssi, device.ReportDataTxPower: "-12"},
+	}
+}
+
+func TestNewWithRSSISettings(t *testing.T) {
+	tr, err := newBTTracker(config.Settings{})
+	assert.NoError(t, err)
+	tracker := tr.(*btTracker)
+	assert.Equal(t, defaultRSSISmoothing, tracker.rssiSmoothing)
+	assert.Nil(t, tracker.minRSSI)
+	assert.Equal(t, defaultRSSIThreshold, tracker.rssiThreshold)
+	assert.Equal(t, defaultReportInterval, tracker.reportInterval)
+
+	tr, err = newBTTracker(config.Settings{"rssi_smoothing": "0.5", "min_rssi": "-80", "rssi_threshold": "3", "report_interval": "30s"})
+	assert.NoError(t, err)
+	tracker = tr.(*btTracker)
+	assert.Equal(t, 0.5, tracker.rssiSmoothing)
+	assert.Equal(t, -80, *tracker.minRSSI)
+	assert.Equal(t, 3, tracker.rssiThreshold)
+	assert.Equal(t, 30*time.Second, tracker.reportInterval)
+
+	_, err = newBTTracker(config.Settings{"report_interval": "often"})
+	assert.EqualError(t, err, "invalid report_interval setting value: often")
+}
+
+func TestReportSightingsSmoothesRSSI(t *testing.T) {
+	tracker, reported, _ := newTestTracker(&fakeBlueZ{}, 1)
+	tracker.rssiSmoothing = 0.5
+
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-60")})
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-80")})
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:02", "-80")})
+
+	assert.Equal(t, 3, len(*reported))
+	assert.Equal(t, "-60", (*reported)[0].Data[device.ReportDataRSSI])
+	assert.Equal(t, "-70", (*reported)[1].Data[device.ReportDataRSSI])
+	assert.Equal(t, "-80", (*reported)[2].Data[device.ReportDataRSSI])
+	assert.Equal(t, "1.9", (*reported)[0].Data[device.ReportDataDistance])
+
+	// a stale reading is not taken into account
+	tracker.readings["AA:BB:CC:DD:EE:01"] = rssiReading{value: -60, updatedAt: time.Now().Add(-rssiMaxAge)}
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-90")})
+	assert.Equal(t, "-90", (*reported)[3].Data[device.ReportDataRSSI])
+}
+
+func TestReportSightingsWithMinRSSI(t *testing.T) {
+	tracker, reported, _ := newTestTracker(&fakeBlueZ{}, 1)
+	minRSSI := -75
+	tracker.minRSSI = &minRSSI
+
+	tracker.reportSightings([]model.DetectedInterface{
+		sighting("AA:BB:CC:DD:EE:01", "-60"),
+		sighting("AA:BB:CC:DD:EE:02", "-90"),
+		{Interface: model.Interface{Type: model.InterfaceBluetooth, MACAddress: "AA:BB:CC:DD:EE:03"}},
+	})
+	assert.Equal(t, 2, len(*reported))
+	assert.Equal(t, "AA:BB:CC:DD:EE:01", (*reported)[0].MACAddress)
+	assert.Equal(t, "AA:BB:CC:DD:EE:03", (*reported)[1].MACAddress)
+}
+
+func TestReportSightingsThrottled(t *testing.T) {
+	tracker, reported, _ := newTestTracker(&fakeBlueZ{}, 1)
+	tracker.rssiSmoothing = 1
+	tracker.rssiThreshold = 5
+	tracker.reportInterval = time.Minute
+
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-60")})
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-62")})
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-58")})
+	assert.Equal(t, 1, len(*reported))
+
+	// the RSSI changed enough
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-70")})
+	assert.Equal(t, 2, len(*reported))
+	assert.Equal(t, "-70", (*reported)[1].Data[device.ReportDataRSSI])
+
+	// once per report interval
+	r := tracker.readings["AA:BB:CC:DD:EE:01"]
+	r.reportedAt = time.Now().Add(-time.Minute)
+	tracker.readings["AA:BB:CC:DD:EE:01"] = r
+	tracker.reportSightings([]model.DetectedInterface{sighting("AA:BB:CC:DD:EE:01", "-71")})
+	assert.Equal(t, 3, len(*reported))
+}
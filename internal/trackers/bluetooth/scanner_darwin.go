@@ -2,7 +2,9 @@ package bluetooth
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -87,15 +89,30 @@ func (mgr *btDarwinManager) DidDiscoverPeripheral(cmgr cbgo.CentralManager, p cb
 	for i, u := range f.ServiceUUIDs {
 		props[fmt.Sprintf("ServiceUUID-%d", i)] = u.String()
 	}
-	for _, data := range f.ServiceData {
-		props[fmt.Sprintf("ServiceData-%s", data.UUID.String())] = string(data.Data)
-	}
-	itf := model.Interface{
+	itf := model.DetectedInterface{Interface: model.Interface{
 		Type:       model.InterfaceBluetooth,
 		MACAddress: p.Identifier().String(), // on OSX this is not a MACAddress but a UUID
+	}, Data: props}
+	for _, data := range f.ServiceData {
+		decodeServiceData(fullUUID(data.UUID.String()), data.Data, &itf)
+	}
+	if len(f.ManufacturerData) >= 2 {
+		company := binary.LittleEndian.Uint16(f.ManufacturerData)
+		decodeManufacturerData(company, f.ManufacturerData[2:], &itf)
 	}
-	mgr.report([]model.DetectedInterface{{Interface: itf, Data: props}})
+	props[device.ReportDataRSSI] = strconv.Itoa(rssi)
+	if f.TxPowerLevel != nil {
+		props[device.ReportDataTxPower] = strconv.Itoa(*f.TxPowerLevel)
+	}
+	mgr.report([]model.DetectedInterface{itf})
+}
 
+// fullUUID expands a 16 bits UUID (e.g. "FEAA") using the Bluetooth base UUID.
+func fullUUID(u string) string {
+	if len(u) == 4 {
+		return "0000" + u + "-0000-1000-8000-00805f9b34fb"
+	}
+	return u
 }
 
 func (mgr *btDarwinManager) stopScan() {
@@ -3,9 +3,15 @@ package bluetooth
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/godbus/dbus/v5"
 	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
 	"github.com/muka/go-bluetooth/bluez/profile/adapter"
 	linux_device "github.com/muka/go-bluetooth/bluez/profile/device"
 	log "github.com/sirupsen/logrus"
@@ -16,6 +22,14 @@ import (
 type btLinuxManager struct {
 	a      *adapter.Adapter1
 	cancel func()
+
+	mutex   sync.Mutex
+	watched map[dbus.ObjectPath]watchedDevice
+}
+
+type watchedDevice struct {
+	dev     *linux_device.Device1
+	changes chan *bluez.PropertyChanged
 }
 
 func newBtManager() btManager {
@@ -25,7 +39,8 @@ func newBtManager() btManager {
 		return nil
 	}
 	return &btLinuxManager{
-		a: a,
+		a:       a,
+		watched: make(map[dbus.ObjectPath]watchedDevice),
 	}
 }
 
@@ -42,6 +57,7 @@ func (mgr *btLinuxManager) scan(report device.ReportPresenceFunc, ctx context.Co
 	go func() {
 		for ev := range discovery {
 			if ev.Type == adapter.DeviceRemoved {
+				mgr.unwatch(ev.Path)
 				continue
 			}
 
@@ -56,37 +72,118 @@ func (mgr *btLinuxManager) scan(report device.ReportPresenceFunc, ctx context.Co
 				continue
 			}
 
-			props := make(map[string]string)
-			props[device.ReportDataSuggestedIdentifier] = dev.Properties.Address
-			props["AddressType"] = dev.Properties.AddressType
-
-			v := strings.TrimSpace(dev.Properties.Name)
-			if len(v) > 0 {
-				props[device.ReportDataSuggestedDescription] = v
-			} else {
-				v = strings.TrimSpace(dev.Properties.Alias)
-				if len(v) > 0 {
-					props[device.ReportDataSuggestedDescription] = v
-				}
-			}
+			itf := toDetectedInterface(dev.Properties)
+			report([]model.DetectedInterface{itf})
+			mgr.watch(dev, itf, report)
+		}
+	}()
+
+	return nil
+}
 
-			for i, u := range dev.Properties.UUIDs {
-				props[fmt.Sprintf("ServiceUUID-%d", i)] = u
+// watch reports the device again whenever its RSSI changes (i.e. when
+// advertisements are received), as discovery only reports new devices.
+func (mgr *btLinuxManager) watch(dev *linux_device.Device1, itf model.DetectedInterface, report device.ReportPresenceFunc) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, found := mgr.watched[dev.Path()]; found {
+		return
+	}
+	changes, err := dev.WatchProperties()
+	if err != nil {
+		log.Warnf("%s: %s", dev.Path(), err)
+		return
+	}
+	mgr.watched[dev.Path()] = watchedDevice{dev: dev, changes: changes}
+
+	go func() {
+		for change := range changes {
+			if change == nil {
+				return
 			}
-			for uuid, d := range dev.Properties.ServiceData {
-				props[fmt.Sprintf("ServiceData-%s", uuid)] = fmt.Sprint(d)
+			if change.Name != "RSSI" {
+				continue
+			}
+			if rssi, ok := change.Value.(int16); ok {
+				updated := itf
+				updated.Data = maps.Clone(itf.Data)
+				updated.Data[device.ReportDataRSSI] = strconv.Itoa(int(rssi))
+				report([]model.DetectedInterface{updated})
 			}
-			report([]model.DetectedInterface{{Interface: model.Interface{
-				Type:       model.InterfaceBluetooth,
-				MACAddress: dev.Properties.Address,
-			}, Data: props}})
 		}
 	}()
+}
 
-	return nil
+func (mgr *btLinuxManager) unwatch(path dbus.ObjectPath) {
+	mgr.mutex.Lock()
+	w, found := mgr.watched[path]
+	delete(mgr.watched, path)
+	mgr.mutex.Unlock()
+	if found {
+		w.dev.UnwatchProperties(w.changes)
+	}
+}
+
+func toDetectedInterface(p *linux_device.Device1Properties) model.DetectedInterface {
+	props := make(map[string]string)
+	props[device.ReportDataSuggestedIdentifier] = p.Address
+	props["AddressType"] = p.AddressType
+
+	v := strings.TrimSpace(p.Name)
+	if len(v) > 0 {
+		props[device.ReportDataSuggestedDescription] = v
+	} else {
+		v = strings.TrimSpace(p.Alias)
+		if len(v) > 0 {
+			props[device.ReportDataSuggestedDescription] = v
+		}
+	}
+
+	for i, u := range p.UUIDs {
+		props[fmt.Sprintf("ServiceUUID-%d", i)] = u
+	}
+	itf := model.DetectedInterface{Interface: model.Interface{
+		Type:       model.InterfaceBluetooth,
+		MACAddress: p.Address,
+	}, Data: props}
+	for uuid, v := range p.ServiceData {
+		if b, ok := toBytes(v); ok {
+			decodeServiceData(uuid, b, &itf)
+		}
+	}
+	for company, v := range p.ManufacturerData {
+		if b, ok := toBytes(v); ok {
+			decodeManufacturerData(company, b, &itf)
+		}
+	}
+	// BlueZ reports 0 when the value is not known (e.g. not advertised)
+	if p.RSSI != 0 {
+		props[device.ReportDataRSSI] = strconv.Itoa(int(p.RSSI))
+	}
+	if p.TxPower != 0 {
+		props[device.ReportDataTxPower] = strconv.Itoa(int(p.TxPower))
+	}
+	return itf
+}
+
+// toBytes converts the value of an advertisement data entry, as received from D-Bus.
+func toBytes(v interface{}) ([]byte, bool) {
+	switch b := v.(type) {
+	case []byte:
+		return b, true
+	case dbus.Variant:
+		return toBytes(b.Value())
+	}
+	return nil, false
 }
 
 func (mgr *btLinuxManager) stopScan() {
 	mgr.cancel()
+	mgr.mutex.Lock()
+	paths := slices.Collect(maps.Keys(mgr.watched))
+	mgr.mutex.Unlock()
+	for _, path := range paths {
+		mgr.unwatch(path)
+	}
 	api.Exit()
 }
@@ -2,7 +2,10 @@ package bluetooth
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/touchardv/myhome-presence/internal/config"
@@ -11,21 +14,102 @@ import (
 
 // EnableTracker registers the "bluetooth" tracker so that it can be used.
 func EnableTracker() {
-	device.Register("bluetooth", newBTTracker)
+	device.Register("bluetooth", newBTTracker, schema)
 }
 
-type btTracker struct{}
+var schema = config.Schema{
+	{Name: "ping_timeout", Type: config.TypeDuration, Default: defaultPingTimeout.String(), Description: "The timeout of a ping (connection) attempt."},
+	{Name: "ping_concurrency", Type: config.TypeInt, Default: strconv.Itoa(defaultPingConcurrency), Description: "The maximum number of concurrent pings."},
+	{Name: "rssi_smoothing", Type: config.TypeFloat, Default: strconv.FormatFloat(defaultRSSISmoothing, 'f', -1, 64), Description: "The weight (between 0 and 1) of a new RSSI measurement."},
+	{Name: "min_rssi", Type: config.TypeInt, Description: "The minimum RSSI (in dBm) of a sighting."},
+	{Name: "rssi_threshold", Type: config.TypeInt, Default: strconv.Itoa(defaultRSSIThreshold), Description: "The change (in dB) of the RSSI of a device reported right away."},
+	{Name: "report_interval", Type: config.TypeDuration, Default: defaultReportInterval.String(), Description: "The interval between the reports of a device whose RSSI does not change much."},
+}
+
+type btTracker struct {
+	mutex       sync.Mutex
+	report      device.ReportPresenceFunc
+	prober      prober
+	probing     map[string]bool
+	probes      chan struct{}
+	pingTimeout time.Duration
 
-func newBTTracker(config.Settings) device.Tracker {
-	return &btTracker{}
+	readings      map[string]rssiReading
+	rssiSmoothing float64
+	minRSSI       *int
+
+	rssiThreshold  int
+	reportInterval time.Duration
+}
+
+func newBTTracker(cfg config.Settings) (device.Tracker, error) {
+	timeout := defaultPingTimeout
+	if v, found := cfg["ping_timeout"]; found {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ping_timeout setting value: %s", v)
+		}
+		timeout = d
+	}
+	concurrency := defaultPingConcurrency
+	if v, found := cfg["ping_concurrency"]; found {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid ping_concurrency setting value: %s", v)
+		}
+		concurrency = n
+	}
+	t := &btTracker{
+		prober:        newProber(),
+		probing:       make(map[string]bool),
+		probes:        make(chan struct{}, concurrency),
+		pingTimeout:   timeout,
+		readings:      make(map[string]rssiReading),
+		rssiSmoothing: defaultRSSISmoothing,
+
+		rssiThreshold:  defaultRSSIThreshold,
+		reportInterval: defaultReportInterval,
+	}
+	if v, found := cfg["rssi_smoothing"]; found {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			return nil, fmt.Errorf("invalid rssi_smoothing setting value: %s", v)
+		}
+		t.rssiSmoothing = f
+	}
+	if v, found := cfg["min_rssi"]; found {
+		n, err := strconv.Atoi(v)
+		if err != nil || n >= 0 {
+			return nil, fmt.Errorf("invalid min_rssi setting value: %s", v)
+		}
+		t.minRSSI = &n
+	}
+	if v, found := cfg["rssi_threshold"]; found {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid rssi_threshold setting value: %s", v)
+		}
+		t.rssiThreshold = n
+	}
+	if v, found := cfg["report_interval"]; found {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid report_interval setting value: %s", v)
+		}
+		t.reportInterval = d
+	}
+	return t, nil
 }
 
 func (t *btTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
 	defer wg.Done()
 
 	log.Info("Starting: bluetooth tracker")
+	t.mutex.Lock()
+	t.report = deviceReport
+	t.mutex.Unlock()
 	mgr := newBtManager()
-	err := mgr.scan(deviceReport, ctx)
+	err := mgr.scan(t.reportSightings, ctx)
 	if err != nil {
 		log.Warn("Scan failed: ", err)
 	} else {
@@ -33,6 +117,9 @@ func (t *btTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Con
 		mgr.stopScan()
 	}
 
+	t.mutex.Lock()
+	t.report = nil
+	t.mutex.Unlock()
 	log.Info("Stopped: bluetooth tracker")
 	return nil
 }
@@ -0,0 +1,139 @@
+package checkin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type event int
+
+const (
+	eventNone event = iota
+	eventEnter
+	eventLeave
+)
+
+type source int
+
+const (
+	sourceGeneric source = iota
+	sourceOwnTracks
+	sourceGPSLogger
+)
+
+// checkin is a parsed check-in: either an explicit event (e.g. a geofence
+// transition) or a location, or both.
+type checkin struct {
+	source   source
+	event    event
+	location *location
+	accuracy float64
+}
+
+var enterEvents = []string{"enter", "entered", "arrive", "arrived", "home", "present"}
+
+var leaveEvents = []string{"leave", "left", "exit", "depart", "departed", "not_home", "away", "absent"}
+
+func toEvent(v string) (event, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case len(v) == 0:
+		return eventNone, nil
+	case slices.Contains(enterEvents, v):
+		return eventEnter, nil
+	case slices.Contains(leaveEvents, v):
+		return eventLeave, nil
+	}
+	return eventNone, fmt.Errorf("invalid event: %s", v)
+}
+
+type genericCheckin struct {
+	Event     string   `json:"event"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Accuracy  float64  `json:"accuracy"`
+}
+
+// parseCheckin parses the body of a POST /api/checkin request, e.g.
+// {"event": "enter"} or {"latitude": 50.85, "longitude": 4.35, "accuracy": 20}.
+func parseCheckin(req *http.Request) (checkin, error) {
+	c := checkin{source: sourceGeneric}
+	body := genericCheckin{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return c, fmt.Errorf("invalid check-in: %w", err)
+	}
+	var err error
+	if c.event, err = toEvent(body.Event); err != nil {
+		return c, err
+	}
+	if body.Latitude != nil && body.Longitude != nil {
+		c.location = &location{latitude: *body.Latitude, longitude: *body.Longitude}
+		c.accuracy = body.Accuracy
+	}
+	if c.event == eventNone && c.location == nil {
+		return c, errors.New("missing event or location")
+	}
+	return c, nil
+}
+
+type ownTracksMessage struct {
+	Type      string   `json:"_type"`
+	Event     string   `json:"event"`
+	Desc      string   `json:"desc"`
+	Latitude  *float64 `json:"lat"`
+	Longitude *float64 `json:"lon"`
+	Accuracy  float64  `json:"acc"`
+	InRegions []string `json:"inregions"`
+}
+
+// parseOwnTracks parses a message sent by the OwnTracks app (in HTTP mode):
+// region transitions for the home region, and locations.
+func parseOwnTracks(req *http.Request, homeRegion string) (checkin, error) {
+	c := checkin{source: sourceOwnTracks}
+	m := ownTracksMessage{}
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		return c, fmt.Errorf("invalid OwnTracks message: %w", err)
+	}
+	switch m.Type {
+	case "transition":
+		if strings.EqualFold(m.Desc, homeRegion) {
+			var err error
+			if c.event, err = toEvent(m.Event); err != nil {
+				return c, err
+			}
+		}
+	case "location":
+		if slices.ContainsFunc(m.InRegions, func(r string) bool { return strings.EqualFold(r, homeRegion) }) {
+			c.event = eventEnter
+		} else if m.Latitude != nil && m.Longitude != nil {
+			c.location = &location{latitude: *m.Latitude, longitude: *m.Longitude}
+			c.accuracy = m.Accuracy
+		}
+	}
+	// other messages (e.g. waypoints, cards) are accepted but ignored
+	return c, nil
+}
+
+// parseGPSLogger parses a request sent by the GPSLogger app (custom URL logging),
+// using the query or the form parameters: lat, lon and acc.
+func parseGPSLogger(req *http.Request) (checkin, error) {
+	c := checkin{source: sourceGPSLogger}
+	if err := req.ParseForm(); err != nil {
+		return c, err
+	}
+	lat, errLat := strconv.ParseFloat(req.Form.Get("lat"), 64)
+	lon, errLon := strconv.ParseFloat(req.Form.Get("lon"), 64)
+	if errLat != nil || errLon != nil {
+		return c, errors.New("missing or invalid lat/lon parameters")
+	}
+	c.location = &location{latitude: lat, longitude: lon}
+	if v := req.Form.Get("acc"); len(v) > 0 {
+		c.accuracy, _ = strconv.ParseFloat(v, 64)
+	}
+	return c, nil
+}
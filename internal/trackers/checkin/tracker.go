@@ -0,0 +1,255 @@
+package checkin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "checkin" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newCheckinTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "token.*", Type: config.TypeString, Required: true, Secret: true, Description: "The check-in token of a device (token.<device identifier>)."},
+	{Name: "home_latitude", Type: config.TypeFloat, Description: "The latitude of home."},
+	{Name: "home_longitude", Type: config.TypeFloat, Description: "The longitude of home."},
+	{Name: "home_radius", Type: config.TypeFloat, Default: strconv.FormatFloat(defaultHomeRadius, 'f', -1, 64), Description: "The radius (in meters) of home."},
+	{Name: "home_region", Type: config.TypeString, Default: defaultHomeRegion, Description: "The name of the home region."},
+	{Name: "departure_grace_period", Type: config.TypeDuration, Default: defaultDepartureGracePeriod.String(), Description: "How long a device is not considered as present by the other trackers (e.g. still seen by Bluetooth while leaving) after its departure."},
+}
+
+const name = "checkin"
+
+const defaultHomeRadius = 100.0
+
+const defaultHomeRegion = "home"
+
+const defaultDepartureGracePeriod = 10 * time.Minute
+
+var errUnauthorized = errors.New("unauthorized")
+
+type location struct {
+	latitude  float64
+	longitude float64
+}
+
+type checkinTracker struct {
+	tokens     map[string]string
+	home       *location
+	homeRadius float64
+	homeRegion string
+
+	departureGracePeriod time.Duration
+
+	mutex  sync.RWMutex
+	report device.ReportPresenceFunc
+}
+
+func newCheckinTracker(cfg config.Settings) (device.Tracker, error) {
+	t := &checkinTracker{
+		tokens: make(map[string]string),
+
+		departureGracePeriod: defaultDepartureGracePeriod,
+	}
+	// token.<device identifier>
+	for k, v := range cfg {
+		if id, found := strings.CutPrefix(k, "token."); found && len(v) > 0 {
+			t.tokens[v] = id
+		}
+	}
+	if len(t.tokens) == 0 {
+		return nil, errors.New("missing device 'token.<device identifier>' setting")
+	}
+
+	if _, found := cfg["home_latitude"]; found {
+		latitude, err := schema.Float(cfg, "home_latitude")
+		if err != nil {
+			return nil, err
+		}
+		longitude, err := schema.Float(cfg, "home_longitude")
+		if err != nil {
+			return nil, err
+		}
+		t.home = &location{latitude: latitude, longitude: longitude}
+	}
+	radius, err := schema.Float(cfg, "home_radius")
+	if err != nil {
+		return nil, err
+	}
+	t.homeRadius = radius
+	t.homeRegion = schema.Value(cfg, "home_region")
+	if v, found := cfg["departure_grace_period"]; found {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid departure_grace_period setting value: %s", v)
+		}
+		t.departureGracePeriod = d
+	}
+	return t, nil
+}
+
+func (t *checkinTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	t.mutex.Lock()
+	t.report = deviceReport
+	t.mutex.Unlock()
+
+	<-ctx.Done()
+
+	t.mutex.Lock()
+	t.report = nil
+	t.mutex.Unlock()
+	log.Infof("Stopped: %s tracker", name)
+	return nil
+}
+
+func (t *checkinTracker) Ping([]model.Device) {
+	// Nothing to be done here. The devices check in by themselves.
+}
+
+// ServeHTTP handles the check-ins sent to /api/checkin, /api/checkin/owntracks and /api/checkin/gpslogger.
+func (t *checkinTracker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	id, err := t.authenticate(req)
+	if err != nil {
+		rw.Header().Add("WWW-Authenticate", `Basic realm="myhome-presence"`)
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var c checkin
+	switch strings.TrimSuffix(req.URL.Path, "/") {
+	case "":
+		if req.Method != "POST" {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, err = parseCheckin(req)
+	case "/owntracks":
+		if req.Method != "POST" {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, err = parseOwnTracks(req, t.homeRegion)
+	case "/gpslogger":
+		c, err = parseGPSLogger(req)
+	default:
+		http.NotFound(rw, req)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	present, known := t.presence(c)
+	if known {
+		if !t.reportCheckin(id, present) {
+			http.Error(rw, "tracker not started", http.StatusServiceUnavailable)
+			return
+		}
+		log.Debugf("[%s] %s checked in (present=%t)", name, id, present)
+	}
+
+	rw.Header().Add("Content-Type", "application/json")
+	if c.source == sourceOwnTracks {
+		// OwnTracks expects an array (of location updates of other users)
+		rw.Write([]byte("[]"))
+		return
+	}
+	response := checkinResponse{Device: id}
+	if known {
+		response.Present = &present
+	}
+	json.NewEncoder(rw).Encode(response)
+}
+
+type checkinResponse struct {
+	Device  string `json:"device"`
+	Present *bool  `json:"present,omitempty"`
+}
+
+// authenticate returns the identifier of the device associated to the token sent
+// either as a bearer token, a "token" query parameter or a basic auth password.
+func (t *checkinTracker) authenticate(req *http.Request) (string, error) {
+	token := req.URL.Query().Get("token")
+	if v, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); found {
+		token = v
+	} else if _, password, ok := req.BasicAuth(); ok {
+		token = password
+	}
+	if len(token) == 0 {
+		return "", errUnauthorized
+	}
+	for k, id := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(token)) == 1 {
+			return id, nil
+		}
+	}
+	return "", errUnauthorized
+}
+
+// presence tells whether a check-in reports an arrival (true) or a departure
+// (false); when only a location is known, it is compared to the home location.
+func (t *checkinTracker) presence(c checkin) (bool, bool) {
+	switch c.event {
+	case eventEnter:
+		return true, true
+	case eventLeave:
+		return false, true
+	}
+	if c.location == nil || t.home == nil {
+		return false, false
+	}
+	d := distance(*t.home, *c.location)
+	if d <= t.homeRadius {
+		return true, true
+	}
+	if d-c.accuracy > t.homeRadius {
+		return false, true
+	}
+	// the location is not accurate enough
+	return false, false
+}
+
+func (t *checkinTracker) reportCheckin(id string, present bool) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.report == nil {
+		return false
+	}
+	itf := model.DetectedInterface{DeviceID: id, Departed: !present}
+	if !present && t.departureGracePeriod > 0 {
+		itf.Data = map[string]string{device.ReportDataGracePeriod: t.departureGracePeriod.String()}
+	}
+	t.report([]model.DetectedInterface{itf})
+	return true
+}
+
+// distance returns the (haversine) distance in meters between two locations.
+func distance(a location, b location) float64 {
+	const earthRadius = 6371000.0
+	lat1 := a.latitude * math.Pi / 180
+	lat2 := b.latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.longitude - a.longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
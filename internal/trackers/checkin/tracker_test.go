@@ -0,0 +1,230 @@
+package checkin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+var settings = config.Settings{
+	"token.alice-phone": "secret-alice",
+	"token.bob-phone":   "secret-bob",
+	"home_latitude":     "50.8466",
+	"home_longitude":    "4.3528",
+	"home_radius":       "150",
+}
+
+var departureData = map[string]string{device.ReportDataGracePeriod: "10m0s"}
+
+type recorder struct {
+	mutex    sync.Mutex
+	reported []model.DetectedInterface
+}
+
+func (r *recorder) report(itfs []model.DetectedInterface) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reported = append(r.reported, itfs...)
+}
+
+func (r *recorder) reset() []model.DetectedInterface {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	reported := r.reported
+	r.reported = nil
+	return reported
+}
+
+func startTracker(t *testing.T) (*checkinTracker, *recorder, func()) {
+	tr, err := newCheckinTracker(settings)
+	assert.NoError(t, err)
+	tracker := tr.(*checkinTracker)
+	r := &recorder{}
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go tracker.Loop(r.report, ctx, wg)
+	assert.Eventually(t, func() bool {
+		tracker.mutex.RLock()
+		defer tracker.mutex.RUnlock()
+		return tracker.report != nil
+	}, time.Second, time.Millisecond)
+	return tracker, r, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func perform(tracker *checkinTracker, req *http.Request) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	tracker.ServeHTTP(rw, req)
+	return rw
+}
+
+func post(path string, token string, body string) *http.Request {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	if len(token) > 0 {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newCheckinTracker(config.Settings{"token.alice-phone": "secret"})
+	assert.NoError(t, err)
+	tracker := tr.(*checkinTracker)
+	assert.Equal(t, map[string]string{"secret": "alice-phone"}, tracker.tokens)
+	assert.Nil(t, tracker.home)
+	assert.Equal(t, defaultHomeRadius, tracker.homeRadius)
+	assert.Equal(t, defaultHomeRegion, tracker.homeRegion)
+
+	tr, err = newCheckinTracker(settings)
+	assert.NoError(t, err)
+	tracker = tr.(*checkinTracker)
+	assert.Equal(t, &location{latitude: 50.8466, longitude: 4.3528}, tracker.home)
+	assert.Equal(t, 150.0, tracker.homeRadius)
+
+	_, err = newCheckinTracker(config.Settings{})
+	assert.Error(t, err)
+	_, err = newCheckinTracker(config.Settings{"token.alice-phone": "secret", "home_latitude": "50.8466"})
+	assert.EqualError(t, err, "missing 'home_longitude' setting")
+	_, err = newCheckinTracker(config.Settings{"token.alice-phone": "secret", "home_radius": "far"})
+	assert.EqualError(t, err, "invalid home_radius setting value: far")
+	_, err = newCheckinTracker(config.Settings{"token.alice-phone": "secret", "departure_grace_period": "soon"})
+	assert.EqualError(t, err, "invalid departure_grace_period setting value: soon")
+}
+
+func TestDepartureWithoutGracePeriod(t *testing.T) {
+	tracker, r, stop := startTracker(t)
+	defer stop()
+	tracker.departureGracePeriod = 0
+
+	perform(tracker, post("", "secret-alice", `{"event": "leave"}`))
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "alice-phone", Departed: true}}, r.reset())
+}
+
+func TestAuthentication(t *testing.T) {
+	tracker, r, stop := startTracker(t)
+	defer stop()
+
+	response := perform(tracker, post("", "", `{"event": "enter"}`))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+
+	response = perform(tracker, post("", "wrong", `{"event": "enter"}`))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Empty(t, r.reset())
+
+	response = perform(tracker, post("?token=secret-bob", "", `{"event": "enter"}`))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone"}}, r.reset())
+
+	req := post("/owntracks", "", `{"_type": "transition", "event": "enter", "desc": "home"}`)
+	req.SetBasicAuth("alice", "secret-alice")
+	response = perform(tracker, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "alice-phone"}}, r.reset())
+}
+
+func TestCheckin(t *testing.T) {
+	tracker, r, stop := startTracker(t)
+	defer stop()
+
+	response := perform(tracker, post("", "secret-alice", `{"event": "leave"}`))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"device": "alice-phone", "present": false}`, response.Body.String())
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "alice-phone", Departed: true, Data: departureData}}, r.reset())
+
+	// within the home radius
+	response = perform(tracker, post("", "secret-alice", `{"latitude": 50.8470, "longitude": 4.3530, "accuracy": 10}`))
+	assert.JSONEq(t, `{"device": "alice-phone", "present": true}`, response.Body.String())
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "alice-phone"}}, r.reset())
+
+	// far away
+	response = perform(tracker, post("", "secret-alice", `{"latitude": 50.8798, "longitude": 4.7005, "accuracy": 10}`))
+	assert.JSONEq(t, `{"device": "alice-phone", "present": false}`, response.Body.String())
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "alice-phone", Departed: true, Data: departureData}}, r.reset())
+
+	// not accurate enough to decide
+	response = perform(tracker, post("", "secret-alice", `{"latitude": 50.8490, "longitude": 4.3528, "accuracy": 500}`))
+	assert.JSONEq(t, `{"device": "alice-phone"}`, response.Body.String())
+	assert.Empty(t, r.reset())
+
+	response = perform(tracker, post("", "secret-alice", `{"event": "dancing"}`))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = perform(tracker, post("", "secret-alice", `{}`))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = perform(tracker, post("/unknown", "secret-alice", `{}`))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	req, _ := http.NewRequest("GET", "/?token=secret-alice", nil)
+	response = perform(tracker, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Empty(t, r.reset())
+}
+
+func TestOwnTracks(t *testing.T) {
+	tracker, r, stop := startTracker(t)
+	defer stop()
+
+	response := perform(tracker, post("/owntracks", "secret-bob", `{"_type": "transition", "event": "leave", "desc": "Home", "lat": 50.8466, "lon": 4.3528}`))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "[]", response.Body.String())
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone", Departed: true, Data: departureData}}, r.reset())
+
+	// another region
+	perform(tracker, post("/owntracks", "secret-bob", `{"_type": "transition", "event": "enter", "desc": "Work"}`))
+	assert.Empty(t, r.reset())
+
+	perform(tracker, post("/owntracks", "secret-bob", `{"_type": "location", "lat": 50.8798, "lon": 4.7005, "acc": 15, "inregions": ["home"]}`))
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone"}}, r.reset())
+
+	perform(tracker, post("/owntracks", "secret-bob", `{"_type": "location", "lat": 50.8798, "lon": 4.7005, "acc": 15}`))
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone", Departed: true, Data: departureData}}, r.reset())
+
+	perform(tracker, post("/owntracks", "secret-bob", `{"_type": "card", "name": "Bob"}`))
+	assert.Empty(t, r.reset())
+}
+
+func TestGPSLogger(t *testing.T) {
+	tracker, r, stop := startTracker(t)
+	defer stop()
+
+	req, _ := http.NewRequest("GET", "/gpslogger?token=secret-bob&lat=50.8467&lon=4.3527&acc=8", nil)
+	response := perform(tracker, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone"}}, r.reset())
+
+	req = post("/gpslogger", "secret-bob", "lat=50.8798&lon=4.7005")
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	response = perform(tracker, req)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []model.DetectedInterface{{DeviceID: "bob-phone", Departed: true, Data: departureData}}, r.reset())
+
+	req, _ = http.NewRequest("GET", "/gpslogger?token=secret-bob&lat=north", nil)
+	response = perform(tracker, req)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestNotStarted(t *testing.T) {
+	tr, err := newCheckinTracker(settings)
+	assert.NoError(t, err)
+	tracker := tr.(*checkinTracker)
+
+	response := perform(tracker, post("", "secret-alice", `{"event": "enter"}`))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+}
+
+func TestDistance(t *testing.T) {
+	brussels := location{latitude: 50.8466, longitude: 4.3528}
+	leuven := location{latitude: 50.8798, longitude: 4.7005}
+	assert.InDelta(t, 24700, distance(brussels, leuven), 300)
+	assert.Equal(t, 0.0, distance(brussels, brussels))
+}
@@ -0,0 +1,257 @@
+package fritzbox
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const hostsControlURL = "/upnp/control/hosts"
+
+const hostsService = "urn:dslforum-org:service:Hosts:1"
+
+// tr064Client performs SOAP requests against the TR-064 interface of a
+// FRITZ!Box, using HTTP digest authentication.
+type tr064Client struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mutex     sync.Mutex
+	challenge *digestChallenge
+	nc        int
+}
+
+type digestChallenge struct {
+	realm  string
+	nonce  string
+	opaque string
+	qop    string
+}
+
+func newTR064Client(baseURL string, username string, password string) *tr064Client {
+	return &tr064Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type soapFault struct {
+	Code        string `xml:"detail>UPnPError>errorCode"`
+	Description string `xml:"detail>UPnPError>errorDescription"`
+}
+
+// call invokes a SOAP action and returns the output arguments of the response.
+func (c *tr064Client) call(controlURL string, service string, action string, args map[string]string) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, service)
+	for k, v := range args {
+		fmt.Fprintf(&body, "<%s>", k)
+		xml.EscapeText(&body, []byte(v))
+		fmt.Fprintf(&body, "</%s>", k)
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	res, err := c.post(controlURL, fmt.Sprintf("%s#%s", service, action), body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("authentication failed")
+	}
+
+	out, fault, err := parseSOAPResponse(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if fault != nil {
+		return nil, fmt.Errorf("%s failed: %s (%s)", action, fault.Description, fault.Code)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected http response " + res.Status)
+	}
+	return out, nil
+}
+
+func (c *tr064Client) post(path string, soapAction string, body []byte) (*http.Response, error) {
+	url := c.baseURL + path
+	for attempt := 0; attempt < 2; attempt++ {
+		req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
+		req.Header.Add("Content-Type", `text/xml; charset="utf-8"`)
+		req.Header.Add("SOAPAction", soapAction)
+		if auth := c.authorization("POST", path); len(auth) > 0 {
+			req.Header.Add("Authorization", auth)
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusUnauthorized {
+			return res, nil
+		}
+		challenge, ok := parseDigestChallenge(res.Header.Get("WWW-Authenticate"))
+		if !ok || attempt > 0 {
+			return res, nil
+		}
+		// (re-)authenticate using the new challenge (e.g. the nonce expired)
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		c.mutex.Lock()
+		c.challenge = challenge
+		c.nc = 0
+		c.mutex.Unlock()
+	}
+	return nil, errors.New("unreachable")
+}
+
+// authorization computes the digest authorization header value (RFC 2617).
+func (c *tr064Client) authorization(method string, uri string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.challenge == nil {
+		return ""
+	}
+	c.nc++
+	nc := fmt.Sprintf("%08x", c.nc)
+	cnonce := randomHex(8)
+	ha1 := md5Hex(fmt.Sprintf("%s:%s:%s", c.username, c.challenge.realm, c.password))
+	ha2 := md5Hex(fmt.Sprintf("%s:%s", method, uri))
+	var response string
+	if len(c.challenge.qop) > 0 {
+		response = md5Hex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, c.challenge.nonce, nc, cnonce, c.challenge.qop, ha2))
+	} else {
+		response = md5Hex(fmt.Sprintf("%s:%s:%s", ha1, c.challenge.nonce, ha2))
+	}
+
+	auth := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=MD5, response="%s"`,
+		c.username, c.challenge.realm, c.challenge.nonce, uri, response)
+	if len(c.challenge.qop) > 0 {
+		auth += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, c.challenge.qop, nc, cnonce)
+	}
+	if len(c.challenge.opaque) > 0 {
+		auth += fmt.Sprintf(`, opaque="%s"`, c.challenge.opaque)
+	}
+	return auth
+}
+
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+	if !strings.HasPrefix(header, "Digest ") {
+		return nil, false
+	}
+	params := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+	challenge := &digestChallenge{
+		realm:  params["realm"],
+		nonce:  params["nonce"],
+		opaque: params["opaque"],
+	}
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			challenge.qop = "auth"
+		}
+	}
+	return challenge, len(challenge.nonce) > 0
+}
+
+// parseAuthParams parses a comma separated list of key=value or key="value" pairs.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value = s[1 : end+1]
+			s = s[min(end+2, len(s)):]
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// parseSOAPResponse extracts the output arguments (or the fault) from a SOAP response envelope.
+func parseSOAPResponse(r io.Reader) (map[string]string, *soapFault, error) {
+	decoder := xml.NewDecoder(r)
+	out := make(map[string]string)
+	depth := 0
+	var name string
+	var value strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Local == "Fault" {
+				fault := soapFault{}
+				if err := decoder.DecodeElement(&fault, &t); err != nil {
+					return nil, nil, err
+				}
+				return nil, &fault, nil
+			}
+			// Envelope > Body > ActionResponse > argument
+			if depth == 4 {
+				name = t.Name.Local
+				value.Reset()
+			}
+		case xml.CharData:
+			if depth == 4 {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 4 {
+				out[name] = strings.TrimSpace(value.String())
+			}
+			depth--
+		}
+	}
+	return out, nil, nil
+}
+
+func md5Hex(s string) string {
+	h := md5.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,160 @@
+package fritzbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "fritzbox" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newFritzBoxTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "url", Type: config.TypeString, Default: defaultURL, Description: "The TR-064 URL of the FRITZ!Box."},
+	{Name: "username", Type: config.TypeString, Description: "The user name."},
+	{Name: "password", Type: config.TypeString, Required: true, Secret: true, Description: "The password."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the hosts."},
+}
+
+const name = "fritzbox"
+
+const defaultURL = "http://fritz.box:49000"
+
+const defaultPollInterval = 1 * time.Minute
+
+type fritzboxTracker struct {
+	client       *tr064Client
+	pollInterval time.Duration
+}
+
+type host struct {
+	MACAddress    string
+	IPv4Address   string
+	Hostname      string
+	InterfaceType string
+	Active        bool
+}
+
+func newFritzBoxTracker(cfg config.Settings) (device.Tracker, error) {
+	if err := schema.Missing(cfg); err != nil {
+		return nil, err
+	}
+	interval, err := schema.Duration(cfg, "poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	return &fritzboxTracker{
+		client:       newTR064Client(schema.Value(cfg, "url"), cfg["username"], cfg["password"]),
+		pollInterval: interval,
+	}, nil
+}
+
+func (t *fritzboxTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	ticker := time.NewTicker(1 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopped: %s tracker", name)
+			return nil
+
+		case <-ticker.C:
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
+		}
+	}
+}
+
+func (t *fritzboxTracker) poll(deviceReport device.ReportPresenceFunc) {
+	hosts, err := t.hosts()
+	if err != nil {
+		log.Errorf("[%s] %s", name, err)
+		return
+	}
+	itfs := []model.DetectedInterface{}
+	for _, h := range hosts {
+		if h.Active && len(h.MACAddress) > 0 {
+			itfs = append(itfs, toDetectedInterface(h))
+		}
+	}
+	log.Debugf("[%s] detected %d active host(s) out of %d", name, len(itfs), len(hosts))
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+}
+
+func (t *fritzboxTracker) hosts() ([]host, error) {
+	out, err := t.client.call(hostsControlURL, hostsService, "GetHostNumberOfEntries", nil)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(out["NewHostNumberOfEntries"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid number of hosts: %w", err)
+	}
+
+	hosts := make([]host, 0, count)
+	for i := 0; i < count; i++ {
+		out, err = t.client.call(hostsControlURL, hostsService, "GetGenericHostEntry", map[string]string{
+			"NewIndex": strconv.Itoa(i),
+		})
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, host{
+			MACAddress:    out["NewMACAddress"],
+			IPv4Address:   out["NewIPAddress"],
+			Hostname:      out["NewHostName"],
+			InterfaceType: out["NewInterfaceType"],
+			Active:        out["NewActive"] == "1",
+		})
+	}
+	return hosts, nil
+}
+
+func toDetectedInterface(h host) model.DetectedInterface {
+	itf := model.DetectedInterface{
+		Interface: model.Interface{
+			Type:        toInterfaceType(h.InterfaceType),
+			MACAddress:  h.MACAddress,
+			IPv4Address: h.IPv4Address,
+		},
+	}
+	hostname := strings.TrimSpace(h.Hostname)
+	if len(hostname) > 0 {
+		itf.Data = map[string]string{
+			device.ReportDataSuggestedIdentifier:  hostname,
+			device.ReportDataSuggestedDescription: hostname,
+		}
+	}
+	return itf
+}
+
+func toInterfaceType(t string) model.InterfaceType {
+	switch t {
+	case "Ethernet":
+		return model.InterfaceEthernet
+	case "802.11":
+		return model.InterfaceWifi
+	default:
+		return model.InterfaceUnknown
+	}
+}
+
+func (t *fritzboxTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
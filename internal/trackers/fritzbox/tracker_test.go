@@ -0,0 +1,193 @@
+package fritzbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// soapServer is a stand-in for the TR-064 Hosts service of a FRITZ!Box.
+type soapServer struct {
+	t        *testing.T
+	nonce    string
+	requests int
+}
+
+var hostEntries = []map[string]string{
+	{"NewIPAddress": "192.168.178.20", "NewMACAddress": "AA:BB:CC:DD:EE:01", "NewInterfaceType": "802.11", "NewActive": "1", "NewHostName": "phone"},
+	{"NewIPAddress": "192.168.178.21", "NewMACAddress": "AA:BB:CC:DD:EE:02", "NewInterfaceType": "Ethernet", "NewActive": "1", "NewHostName": "nas"},
+	{"NewIPAddress": "192.168.178.22", "NewMACAddress": "AA:BB:CC:DD:EE:03", "NewInterfaceType": "802.11", "NewActive": "0", "NewHostName": "tablet"},
+}
+
+func (s *soapServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	assert.Equal(s.t, "POST", req.Method)
+	assert.Equal(s.t, hostsControlURL, req.URL.Path)
+	if !s.authorized(req) {
+		rw.Header().Add("WWW-Authenticate", fmt.Sprintf(`Digest realm="F!Box SOAP-Auth", nonce="%s", algorithm=MD5, qop="auth"`, s.nonce))
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.requests++
+
+	body, _ := io.ReadAll(req.Body)
+	action := req.Header.Get("SOAPAction")
+	var out string
+	switch action {
+	case hostsService + "#GetHostNumberOfEntries":
+		out = fmt.Sprintf("<NewHostNumberOfEntries>%d</NewHostNumberOfEntries>", len(hostEntries))
+	case hostsService + "#GetGenericHostEntry":
+		var index int
+		fmt.Sscanf(string(body)[strings.Index(string(body), "<NewIndex>"):], "<NewIndex>%d</NewIndex>", &index)
+		if index >= len(hostEntries) {
+			rw.WriteHeader(http.StatusInternalServerError)
+			rw.Write([]byte(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:dslforum-org:control-1-0"><errorCode>713</errorCode><errorDescription>SpecifiedArrayIndexInvalid</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`))
+			return
+		}
+		for k, v := range hostEntries[index] {
+			out += fmt.Sprintf("<%s>%s</%s>", k, v, k)
+		}
+	default:
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	action = action[strings.Index(action, "#")+1:]
+	fmt.Fprintf(rw, `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body>
+<u:%sResponse xmlns:u="%s">%s</u:%sResponse>
+</s:Body>
+</s:Envelope>`, action, hostsService, out, action)
+}
+
+func (s *soapServer) authorized(req *http.Request) bool {
+	params := parseAuthParams(strings.TrimPrefix(req.Header.Get("Authorization"), "Digest "))
+	if params["nonce"] != s.nonce || params["username"] != "admin" {
+		return false
+	}
+	ha1 := md5Hex("admin:F!Box SOAP-Auth:secret")
+	ha2 := md5Hex("POST:" + params["uri"])
+	expected := md5Hex(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+	return params["response"] == expected
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newFritzBoxTracker(config.Settings{"password": "secret"})
+	assert.NoError(t, err)
+	tracker := tr.(*fritzboxTracker)
+	assert.Equal(t, defaultURL, tracker.client.baseURL)
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+
+	tr, err = newFritzBoxTracker(config.Settings{
+		"url":           "http://192.168.178.1:49000/",
+		"username":      "admin",
+		"password":      "secret",
+		"poll_interval": "30s",
+	})
+	assert.NoError(t, err)
+	tracker = tr.(*fritzboxTracker)
+	assert.Equal(t, "http://192.168.178.1:49000", tracker.client.baseURL)
+	assert.Equal(t, "admin", tracker.client.username)
+	assert.Equal(t, 30*time.Second, tracker.pollInterval)
+}
+
+func TestLoop(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := fritzboxTracker{}
+
+	go tracker.Loop(nil, ctx, wg)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestPoll(t *testing.T) {
+	soap := &soapServer{t: t, nonce: "first"}
+	server := httptest.NewServer(soap)
+	defer server.Close()
+
+	tr, err := newFritzBoxTracker(config.Settings{
+		"url":      server.URL,
+		"username": "admin",
+		"password": "secret",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*fritzboxTracker)
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:01", IPv4Address: "192.168.178.20"},
+			Data:      map[string]string{"Identifier": "phone", "Description": "phone"},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceEthernet, MACAddress: "AA:BB:CC:DD:EE:02", IPv4Address: "192.168.178.21"},
+			Data:      map[string]string{"Identifier": "nas", "Description": "nas"},
+		},
+	}, reports[0])
+	assert.Equal(t, 4, soap.requests)
+
+	// the nonce changed => authenticate again
+	soap.nonce = "second"
+	tracker.poll(report)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, 8, soap.requests)
+}
+
+func TestPollWithInvalidCredentials(t *testing.T) {
+	soap := &soapServer{t: t, nonce: "first"}
+	server := httptest.NewServer(soap)
+	defer server.Close()
+
+	tr, err := newFritzBoxTracker(config.Settings{
+		"url":      server.URL,
+		"username": "admin",
+		"password": "wrong",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*fritzboxTracker)
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+	assert.Equal(t, 0, soap.requests)
+}
+
+func TestSOAPFault(t *testing.T) {
+	soap := &soapServer{t: t, nonce: "first"}
+	server := httptest.NewServer(soap)
+	defer server.Close()
+
+	client := newTR064Client(server.URL, "admin", "secret")
+	_, err := client.call(hostsControlURL, hostsService, "GetGenericHostEntry", map[string]string{"NewIndex": "10"})
+	assert.EqualError(t, err, "GetGenericHostEntry failed: SpecifiedArrayIndexInvalid (713)")
+}
+
+func TestParseAuthParams(t *testing.T) {
+	params := parseAuthParams(`realm="F!Box SOAP-Auth", nonce="ABC, 123", algorithm=MD5, qop="auth"`)
+	assert.Equal(t, map[string]string{
+		"realm":     "F!Box SOAP-Auth",
+		"nonce":     "ABC, 123",
+		"algorithm": "MD5",
+		"qop":       "auth",
+	}, params)
+}
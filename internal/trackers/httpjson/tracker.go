@@ -0,0 +1,381 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/internal/jsonpath"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "http-json" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newHTTPJSONTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "name", Type: config.TypeString, Description: "The name of the polled device (the URL host name by default)."},
+	{Name: "url", Type: config.TypeString, Required: true, Description: "The URL returning the JSON list of hosts."},
+	{Name: "method", Type: config.TypeString, Description: "The HTTP method of the request (GET, or POST with a body)."},
+	{Name: "body", Type: config.TypeString, Description: "The body of the request."},
+	{Name: "header.*", Type: config.TypeString, Secret: true, Description: "A header of the request (header.<Name>)."},
+	{Name: "login_url", Type: config.TypeString, Description: "The URL to log in to, before polling."},
+	{Name: "login_method", Type: config.TypeString, Description: "The HTTP method of the login request (POST by default)."},
+	{Name: "login_body", Type: config.TypeString, Secret: true, Description: "The body of the login request."},
+	{Name: "login_header.*", Type: config.TypeString, Secret: true, Description: "A header of the login request (login_header.<Name>)."},
+	{Name: "token_path", Type: config.TypeString, Description: "The path of the token in the login response."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the hosts."},
+	{Name: "devices_path", Type: config.TypeString, Default: defaultDevicesPath, Description: "The path of the hosts in the response."},
+	{Name: "mac_path", Type: config.TypeString, Description: "The path of the MAC address of a host."},
+	{Name: "ip_path", Type: config.TypeString, Description: "The path of the IPv4 address of a host."},
+	{Name: "hostname_path", Type: config.TypeString, Description: "The path of the name of a host."},
+	{Name: "type_path", Type: config.TypeString, Description: "The path of the (interface) type of a host."},
+	{Name: "type_wifi", Type: config.TypeString, Description: "The (comma separated) type values of Wi-Fi hosts."},
+	{Name: "type_ethernet", Type: config.TypeString, Description: "The (comma separated) type values of Ethernet hosts."},
+	{Name: "active_path", Type: config.TypeString, Description: "The path of the active state of a host."},
+	{Name: "active_values", Type: config.TypeString, Default: strings.Join(defaultActiveValues, ","), Description: "The (comma separated) values of an active host."},
+	{Name: "insecure_skip_verify", Type: config.TypeBool, Default: "false", Description: "Whether the TLS certificate is not verified."},
+}
+
+const name = "http-json"
+
+const defaultPollInterval = 1 * time.Minute
+
+const defaultDevicesPath = "$[*]"
+
+// tokenPlaceholder is replaced by the token extracted from the login response.
+const tokenPlaceholder = "{{token}}"
+
+var defaultActiveValues = []string{"true", "1", "yes", "on", "active", "online", "connected"}
+
+var errUnauthorized = errors.New("unauthorized")
+
+type request struct {
+	method  string
+	url     string
+	body    string
+	headers map[string]string
+}
+
+type httpJSONTracker struct {
+	name         string
+	client       *http.Client
+	request      request
+	login        *request
+	tokenPath    *jsonpath.Path
+	token        string
+	loggedIn     bool
+	pollInterval time.Duration
+
+	devicesPath   jsonpath.Path
+	macPath       *jsonpath.Path
+	ipPath        *jsonpath.Path
+	hostnamePath  *jsonpath.Path
+	typePath      *jsonpath.Path
+	activePath    *jsonpath.Path
+	wifiTypes     []string
+	ethernetTypes []string
+	activeValues  []string
+}
+
+func newHTTPJSONTracker(cfg config.Settings) (device.Tracker, error) {
+	t := &httpJSONTracker{
+		request:      newRequest("", cfg),
+		devicesPath:  jsonpath.MustCompile(defaultDevicesPath),
+		activeValues: schema.List(cfg, "active_values"),
+	}
+	if len(t.request.url) == 0 {
+		return nil, errors.New("missing 'url' setting")
+	}
+	t.name = cfg["name"]
+	if len(t.name) == 0 {
+		if u, err := url.Parse(t.request.url); err == nil {
+			t.name = u.Hostname()
+		}
+	}
+	var err error
+	if _, found := cfg["login_url"]; found {
+		login := newRequest("login_", cfg)
+		t.login = &login
+		if t.tokenPath, err = pathSetting("token_path", cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.pollInterval, err = schema.Duration(cfg, "poll_interval"); err != nil {
+		return nil, err
+	}
+	p, err := pathSetting("devices_path", cfg)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		t.devicesPath = *p
+	}
+	for _, s := range []struct {
+		key  string
+		path **jsonpath.Path
+	}{
+		{"mac_path", &t.macPath},
+		{"ip_path", &t.ipPath},
+		{"hostname_path", &t.hostnamePath},
+		{"type_path", &t.typePath},
+		{"active_path", &t.activePath},
+	} {
+		if *s.path, err = pathSetting(s.key, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if t.macPath == nil && t.ipPath == nil {
+		return nil, errors.New("missing 'mac_path' or 'ip_path' setting")
+	}
+	t.wifiTypes = schema.List(cfg, "type_wifi")
+	t.ethernetTypes = schema.List(cfg, "type_ethernet")
+	insecure, err := schema.Bool(cfg, "insecure_skip_verify")
+	if err != nil {
+		return nil, err
+	}
+	jar, _ := cookiejar.New(nil)
+	t.client = &http.Client{
+		Jar:     jar,
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		},
+	}
+	return t, nil
+}
+
+// newRequest builds a request from the settings having the given prefix
+// (url, method, body and header.<Name>).
+func newRequest(prefix string, cfg config.Settings) request {
+	r := request{
+		url:     cfg[prefix+"url"],
+		method:  cfg[prefix+"method"],
+		body:    cfg[prefix+"body"],
+		headers: make(map[string]string),
+	}
+	if len(r.method) == 0 {
+		r.method = "GET"
+		if len(prefix) > 0 || len(r.body) > 0 {
+			r.method = "POST"
+		}
+	}
+	r.method = strings.ToUpper(r.method)
+	for k, v := range cfg {
+		if h, found := strings.CutPrefix(k, prefix+"header."); found {
+			r.headers[h] = v
+		}
+	}
+	return r
+}
+
+func (t *httpJSONTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker (%s)", name, t.name)
+	ticker := time.NewTicker(1 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopped: %s tracker (%s)", name, t.name)
+			return nil
+
+		case <-ticker.C:
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
+		}
+	}
+}
+
+func (t *httpJSONTracker) poll(deviceReport device.ReportPresenceFunc) {
+	doc, err := t.fetch()
+	if err != nil {
+		log.Errorf("[%s] %s: %s", name, t.name, err)
+		return
+	}
+	items := t.devicesPath.Eval(doc)
+	itfs := []model.DetectedInterface{}
+	for _, item := range items {
+		if itf, ok := t.toDetectedInterface(item); ok {
+			itfs = append(itfs, itf)
+		}
+	}
+	log.Debugf("[%s] %s: detected %d device(s) out of %d", name, t.name, len(itfs), len(items))
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+}
+
+// fetch retrieves the JSON document listing the devices, logging in first
+// when needed or when the current token/session was rejected.
+func (t *httpJSONTracker) fetch() (interface{}, error) {
+	if t.login != nil && !t.loggedIn {
+		if err := t.authenticate(); err != nil {
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+	}
+	body, err := t.do(t.request)
+	if errors.Is(err, errUnauthorized) && t.login != nil {
+		log.Debugf("[%s] %s: session was rejected", name, t.name)
+		t.loggedIn = false
+		if err = t.authenticate(); err != nil {
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+		body, err = t.do(t.request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(body)
+}
+
+func (t *httpJSONTracker) authenticate() error {
+	body, err := t.do(*t.login)
+	if err != nil {
+		return err
+	}
+	if t.tokenPath != nil {
+		doc, err := decode(body)
+		if err != nil {
+			return err
+		}
+		token := t.tokenPath.First(doc)
+		if len(token) == 0 {
+			return errors.New("no token found at " + t.tokenPath.String())
+		}
+		t.token = token
+	}
+	t.loggedIn = true
+	return nil
+}
+
+func (t *httpJSONTracker) do(r request) ([]byte, error) {
+	var body io.Reader
+	if len(r.body) > 0 {
+		body = strings.NewReader(t.expand(r.body))
+	}
+	req, err := http.NewRequest(r.method, t.expand(r.url), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+	if len(r.body) > 0 {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, t.expand(v))
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return nil, errUnauthorized
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("unexpected http response " + res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (t *httpJSONTracker) expand(s string) string {
+	return strings.ReplaceAll(s, tokenPlaceholder, t.token)
+}
+
+func (t *httpJSONTracker) toDetectedInterface(item interface{}) (model.DetectedInterface, bool) {
+	itf := model.DetectedInterface{}
+	if t.activePath != nil {
+		v := t.activePath.First(item)
+		if !slices.ContainsFunc(t.activeValues, func(s string) bool { return strings.EqualFold(s, v) }) {
+			return itf, false
+		}
+	}
+	itf.MACAddress = normalizeMAC(first(t.macPath, item))
+	itf.IPv4Address = first(t.ipPath, item)
+	if len(itf.MACAddress) == 0 && len(itf.IPv4Address) == 0 {
+		return itf, false
+	}
+	itf.Type = t.toInterfaceType(first(t.typePath, item))
+	if hostname := first(t.hostnamePath, item); len(hostname) > 0 {
+		itf.Data = map[string]string{
+			device.ReportDataSuggestedIdentifier:  hostname,
+			device.ReportDataSuggestedDescription: hostname,
+		}
+	}
+	return itf, true
+}
+
+func (t *httpJSONTracker) toInterfaceType(v string) model.InterfaceType {
+	matches := func(s string) bool { return strings.EqualFold(s, v) }
+	switch {
+	case len(v) == 0:
+		return model.InterfaceUnknown
+	case slices.ContainsFunc(t.wifiTypes, matches):
+		return model.InterfaceWifi
+	case slices.ContainsFunc(t.ethernetTypes, matches):
+		return model.InterfaceEthernet
+	default:
+		return model.InterfaceUnknown
+	}
+}
+
+func (t *httpJSONTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
+
+func decode(body []byte) (interface{}, error) {
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return doc, nil
+}
+
+func first(p *jsonpath.Path, item interface{}) string {
+	if p == nil {
+		return ""
+	}
+	return strings.TrimSpace(p.First(item))
+}
+
+func normalizeMAC(v string) string {
+	if hw, err := net.ParseMAC(v); err == nil {
+		return hw.String()
+	}
+	return v
+}
+
+func pathSetting(key string, cfg config.Settings) (*jsonpath.Path, error) {
+	v, found := cfg[key]
+	if !found {
+		return nil, nil
+	}
+	p, err := jsonpath.Compile(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s setting value: %w", key, err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,211 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// ispBox is a stand-in for a router exposing its hosts through a token protected JSON API.
+type ispBox struct {
+	t      *testing.T
+	token  string
+	logins int
+}
+
+func (b *ispBox) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/api/login":
+		assert.Equal(b.t, "POST", req.Method)
+		assert.Equal(b.t, "application/json", req.Header.Get("Content-Type"))
+		credentials := map[string]string{}
+		json.NewDecoder(req.Body).Decode(&credentials)
+		if credentials["username"] != "admin" || credentials["password"] != "secret" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		b.logins++
+		b.token = "token-" + string(rune('0'+b.logins))
+		rw.Write([]byte(`{"data": {"token": "` + b.token + `"}}`))
+
+	case "/api/hosts":
+		assert.Equal(b.t, "GET", req.Method)
+		assert.Equal(b.t, "myhome-presence", req.Header.Get("X-Client"))
+		if len(b.token) == 0 || req.Header.Get("Authorization") != "Bearer "+b.token {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Write([]byte(`{"data": {"hosts": [
+			{"macAddress": "AA-BB-CC-DD-EE-01", "ipAddress": "192.10.20.30", "hostName": "phone", "interfaceType": "WiFi 5GHz", "active": true},
+			{"macAddress": "AA-BB-CC-DD-EE-02", "ipAddress": "192.10.20.31", "hostName": "nas", "interfaceType": "Ethernet", "active": true},
+			{"macAddress": "AA-BB-CC-DD-EE-03", "ipAddress": "192.10.20.32", "hostName": "tablet", "interfaceType": "WiFi 2.4GHz", "active": false},
+			{"macAddress": "AA-BB-CC-DD-EE-04", "ipAddress": "", "hostName": "", "interfaceType": "", "active": true},
+			{"hostName": "ghost", "active": true}
+		]}}`))
+
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newSettings(url string) config.Settings {
+	return config.Settings{
+		"url":                   url + "/api/hosts",
+		"header.Authorization":  "Bearer {{token}}",
+		"header.X-Client":       "myhome-presence",
+		"login_url":             url + "/api/login",
+		"login_body":            `{"username": "admin", "password": "secret"}`,
+		"token_path":            "$.data.token",
+		"devices_path":          "$.data.hosts[*]",
+		"mac_path":              "macAddress",
+		"ip_path":               "ipAddress",
+		"hostname_path":         "hostName",
+		"type_path":             "interfaceType",
+		"type_wifi":             "WiFi 2.4GHz, WiFi 5GHz",
+		"type_ethernet":         "Ethernet",
+		"active_path":           "active",
+		"login_header.X-Client": "myhome-presence",
+		"insecure_skip_verify":  "false",
+		"poll_interval":         "30s",
+		"name":                  "isp-box",
+	}
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newHTTPJSONTracker(config.Settings{
+		"url":      "http://192.10.20.1/dhcp/leases",
+		"mac_path": "$.mac",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*httpJSONTracker)
+	assert.Equal(t, request{method: "GET", url: "http://192.10.20.1/dhcp/leases", headers: map[string]string{}}, tracker.request)
+	assert.Equal(t, "192.10.20.1", tracker.name)
+	assert.Nil(t, tracker.login)
+	assert.Equal(t, defaultDevicesPath, tracker.devicesPath.String())
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+
+	tr, err = newHTTPJSONTracker(newSettings("http://192.10.20.1"))
+	assert.NoError(t, err)
+	tracker = tr.(*httpJSONTracker)
+	assert.Equal(t, "isp-box", tracker.name)
+	assert.Equal(t, request{
+		method:  "GET",
+		url:     "http://192.10.20.1/api/hosts",
+		headers: map[string]string{"Authorization": "Bearer {{token}}", "X-Client": "myhome-presence"},
+	}, tracker.request)
+	assert.Equal(t, &request{
+		method:  "POST",
+		url:     "http://192.10.20.1/api/login",
+		body:    `{"username": "admin", "password": "secret"}`,
+		headers: map[string]string{"X-Client": "myhome-presence"},
+	}, tracker.login)
+	assert.Equal(t, 30*time.Second, tracker.pollInterval)
+	assert.Equal(t, []string{"WiFi 2.4GHz", "WiFi 5GHz"}, tracker.wifiTypes)
+
+	_, err = newHTTPJSONTracker(config.Settings{"url": "http://192.10.20.1/dhcp/leases"})
+	assert.EqualError(t, err, "missing 'mac_path' or 'ip_path' setting")
+}
+
+func TestLoop(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := httpJSONTracker{}
+
+	go tracker.Loop(nil, ctx, wg)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestPoll(t *testing.T) {
+	box := &ispBox{t: t}
+	server := httptest.NewServer(box)
+	defer server.Close()
+
+	tr, err := newHTTPJSONTracker(newSettings(server.URL))
+	assert.NoError(t, err)
+	tracker := tr.(*httpJSONTracker)
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, box.logins)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:01", IPv4Address: "192.10.20.30"},
+			Data:      map[string]string{"Identifier": "phone", "Description": "phone"},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceEthernet, MACAddress: "aa:bb:cc:dd:ee:02", IPv4Address: "192.10.20.31"},
+			Data:      map[string]string{"Identifier": "nas", "Description": "nas"},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceUnknown, MACAddress: "aa:bb:cc:dd:ee:04"},
+		},
+	}, reports[0])
+
+	// the session is reused
+	tracker.poll(report)
+	assert.Equal(t, 1, box.logins)
+	assert.Equal(t, 2, len(reports))
+
+	// the token expired => login again
+	box.token = "expired"
+	tracker.poll(report)
+	assert.Equal(t, 2, box.logins)
+	assert.Equal(t, 3, len(reports))
+}
+
+func TestPollWithInvalidCredentials(t *testing.T) {
+	box := &ispBox{t: t}
+	server := httptest.NewServer(box)
+	defer server.Close()
+
+	settings := newSettings(server.URL)
+	settings["login_body"] = `{"username": "admin", "password": "wrong"}`
+	tr, err := newHTTPJSONTracker(settings)
+	assert.NoError(t, err)
+	tracker := tr.(*httpJSONTracker)
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+	assert.False(t, tracker.loggedIn)
+}
+
+func TestPollWithoutLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`[{"mac": "AA:BB:CC:DD:EE:01", "ip": "192.10.20.30", "expires": 3600}]`))
+	}))
+	defer server.Close()
+
+	tr, err := newHTTPJSONTracker(config.Settings{
+		"url":      server.URL,
+		"mac_path": "mac",
+		"ip_path":  "ip",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*httpJSONTracker)
+
+	reported := []model.DetectedInterface{}
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		reported = append(reported, itfs...)
+	})
+	assert.Equal(t, []model.DetectedInterface{
+		{Interface: model.Interface{MACAddress: "aa:bb:cc:dd:ee:01", IPv4Address: "192.10.20.30"}},
+	}, reported)
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/touchardv/myhome-presence/internal/device"
 	"github.com/touchardv/myhome-presence/pkg/model"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
@@ -20,7 +19,7 @@ const defaultPingPacketCount = 5
 const defaultPingPacketDelay = 100 * time.Millisecond
 const data = "AreYouThere"
 
-func (t *ipTracker) receiveLoop(deviceReport device.ReportPresenceFunc) {
+func (t *ipTracker) receiveLoop() {
 	incomingBytes := make([]byte, 32*1024)
 	log.Debug("Receiving ping packets")
 	for {
@@ -36,6 +35,7 @@ func (t *ipTracker) receiveLoop(deviceReport device.ReportPresenceFunc) {
 			}
 			break
 		}
+		now := time.Now()
 		log.Trace("Received ", n, " bytes: ", incomingBytes[:n])
 		if n < 8 {
 			log.Error("Failed parsing icmp message: not enough data")
@@ -44,22 +44,20 @@ func (t *ipTracker) receiveLoop(deviceReport device.ReportPresenceFunc) {
 		var m *icmp.Message
 		if m, err = icmp.ParseMessage(1, incomingBytes[:n]); err != nil {
 			log.Error("Failed parsing icmp message: ", err)
+			continue
 		}
 		if m.Type != ipv4.ICMPTypeEchoReply {
 			log.Trace("Ignore icmp message of type: ", *m)
 			continue
 		}
 		sequenceNumber := binary.BigEndian.Uint16(incomingBytes[6:8])
-		expectedSeq := uint16(atomic.LoadInt32(&t.sequenceNumber))
-		if expectedSeq != sequenceNumber {
-			log.Trace("Ignore echo reply with wrong sequence: ", sequenceNumber, " expected: ", expectedSeq)
-			continue
-		}
 		switch addr := remoteAddr.(type) {
 		case *net.UDPAddr:
+			if !t.rounds.received(sequenceNumber, addr.IP.String(), now) {
+				log.Trace("Ignore unexpected echo reply from: ", addr.IP.String(), " sequence: ", sequenceNumber)
+				continue
+			}
 			log.Debug("Got reply from: ", addr.IP.String())
-			itf := model.Interface{Type: model.InterfaceUnknown, IPv4Address: addr.IP.String()}
-			deviceReport([]model.DetectedInterface{{Interface: itf}})
 		}
 	}
 	log.Debug("Done receiving ping packets")
@@ -67,17 +65,78 @@ func (t *ipTracker) receiveLoop(deviceReport device.ReportPresenceFunc) {
 
 func (t *ipTracker) Ping(devices []model.Device) {
 	log.Debugf("Sending ping to %d device(s)", len(devices))
-	seq := atomic.AddInt32(&t.sequenceNumber, 1)
 	for _, d := range devices {
-		err := t.ping(d, int(seq))
+		err := t.ping(d)
 		if err != nil {
 			log.Warn("Ping failed: ", err)
-			return
+			continue
 		}
 	}
 }
 
-func (t *ipTracker) ping(d model.Device, seq int) error {
+func (t *ipTracker) ping(d model.Device) error {
+	pingRounds := make([]*pingRound, 0)
+	for _, itf := range d.Interfaces {
+		if itf.Type == model.InterfaceEthernet ||
+			itf.Type == model.InterfaceWifi {
+			pingRounds = append(pingRounds, &pingRound{ip: itf.IPv4Address})
+		}
+	}
+	if len(pingRounds) == 0 {
+		return nil
+	}
+	defer t.completeLater(pingRounds, func(ips []string) { t.probe(d, ips) })
+
+	for i := 1; i <= t.pingPacketCount; i++ {
+		for _, round := range pingRounds {
+			log.Debugf("Sending ping packet to %s (%s) %d/%d ", d.Identifier, round.ip, i, t.pingPacketCount)
+			err := t.sendEcho(round)
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+		}
+		if t.pingPacketCount > 1 {
+			time.Sleep(t.pingPacketDelay)
+		}
+	}
+	log.Debugf("Done sending ping packet(s) to %s ", d.Identifier)
+	return nil
+}
+
+// completeLater reports, once the reply timeout has elapsed, the targets that
+// answered together with the measured round-trip time and packet loss, then
+// passes the ones that did not answer to the given function, if any (which is
+// called even when all of them answered).
+func (t *ipTracker) completeLater(pingRounds []*pingRound, unanswered func(ips []string)) {
+	time.AfterFunc(t.pingReplyTimeout, func() {
+		itfs := make([]model.DetectedInterface, 0)
+		ips := make([]string, 0)
+		for _, round := range pingRounds {
+			if itf, ok := t.rounds.complete(round); ok {
+				itfs = append(itfs, itf)
+			} else {
+				ips = append(ips, round.ip)
+			}
+		}
+		if len(itfs) > 0 && t.report != nil {
+			t.report(itfs)
+		}
+		if unanswered != nil {
+			unanswered(ips)
+		}
+	})
+}
+
+func (t *ipTracker) sendEcho(round *pingRound) error {
+	seq := uint16(atomic.AddInt32(&t.sequenceNumber, 1))
+	for !t.rounds.sent(round, seq, time.Now()) {
+		// the sequence number wrapped around while a reply is still awaited
+		seq = uint16(atomic.AddInt32(&t.sequenceNumber, 1))
+	}
+	return t.send(echoRequest(int(seq)), net.ParseIP(round.ip))
+}
+
+func echoRequest(seq int) []byte {
 	message := icmp.Message{
 		Type: ipv4.ICMPTypeEcho,
 		Body: &icmp.Echo{
@@ -87,30 +146,17 @@ func (t *ipTracker) ping(d model.Device, seq int) error {
 		},
 	}
 	outgoingBytes, _ := message.Marshal(nil)
+	return outgoingBytes
+}
 
-	for i := 1; i <= t.pingPacketCount; i++ {
-		for _, itf := range d.Interfaces {
-			if itf.Type == model.InterfaceEthernet ||
-				itf.Type == model.InterfaceWifi {
-				log.Debugf("Sending ping packet to %s (%s) %d/%d ", d.Identifier, itf.IPv4Address, i, t.pingPacketCount)
-				targetIP := net.ParseIP(itf.IPv4Address)
-				targetAddr := &net.UDPAddr{IP: targetIP}
-				_, err := t.socket.WriteTo(outgoingBytes, targetAddr)
-				if err != nil {
-					if errors.Is(err, net.ErrClosed) {
-						return err
-					}
-					msg := err.Error()
-					if !strings.Contains(msg, "sendto: host is down") && !strings.Contains(msg, "no route to host") && !strings.Contains(msg, "sendto: network is unreachable") {
-						log.Warn("Ping failed: ", err)
-					}
-				}
-			}
-		}
-		if t.pingPacketCount > 1 {
-			time.Sleep(t.pingPacketDelay)
+func (t *ipTracker) send(outgoingBytes []byte, targetIP net.IP) error {
+	targetAddr := &net.UDPAddr{IP: targetIP}
+	_, err := t.socket.WriteTo(outgoingBytes, targetAddr)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		msg := err.Error()
+		if !strings.Contains(msg, "sendto: host is down") && !strings.Contains(msg, "no route to host") && !strings.Contains(msg, "sendto: network is unreachable") {
+			log.Warn("Ping failed: ", err)
 		}
 	}
-	log.Debugf("Done sending ping packet(s) to %s ", d.Identifier)
-	return nil
+	return err
 }
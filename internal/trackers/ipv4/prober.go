@@ -0,0 +1,88 @@
+package ipv4
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const defaultProbeTimeout = 1 * time.Second
+
+// PropertyProbePorts is the device property that can be used to override
+// the list of TCP ports to be probed for a given device.
+const PropertyProbePorts = "tcp_probe_ports"
+
+// probe tries to connect to the TCP ports of the given addresses of a device
+// (e.g. the ones not answering pings), reporting the ones answering.
+// The probes share the concurrency limit of the sweep.
+func (t *ipTracker) probe(d model.Device, ips []string) {
+	ports := t.probePorts
+	if v, ok := d.Properties[PropertyProbePorts]; ok {
+		p, err := parsePorts(v)
+		if err != nil {
+			log.Warnf("Invalid %s property value for %s: %s", PropertyProbePorts, d.Identifier, err)
+		} else {
+			ports = p
+		}
+	}
+	if len(ports) == 0 || t.report == nil {
+		return
+	}
+
+	for _, ip := range ips {
+		if len(ip) == 0 {
+			continue
+		}
+		t.probes <- struct{}{}
+		answered := probeTCP(ip, ports, t.probeTimeout)
+		<-t.probes
+		if answered {
+			log.Debugf("Got tcp probe answer from: %s (%s)", d.Identifier, ip)
+			t.report([]model.DetectedInterface{{Interface: model.Interface{Type: model.InterfaceUnknown, IPv4Address: ip}}})
+		}
+	}
+}
+
+// probeTCP tries to connect to the given ports of a host and returns true
+// as soon as the host answers, either by accepting the connection (SYN-ACK)
+// or by actively refusing it (RST).
+func probeTCP(ip string, ports []int, timeout time.Duration) bool {
+	for _, port := range ports {
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp4", addr, timeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		log.Tracef("TCP probe of %s failed: %s", addr, err)
+	}
+	return false
+}
+
+func parsePorts(v string) ([]int, error) {
+	ports := make([]int, 0)
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		}
+		port, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if port <= 0 || port > 65535 {
+			return nil, errors.New("port out of range: " + s)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
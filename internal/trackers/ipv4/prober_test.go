@@ -0,0 +1,74 @@
+package ipv4
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func TestParsePorts(t *testing.T) {
+	ports, err := parsePorts("22,80, 443")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{22, 80, 443}, ports)
+
+	_, err = parsePorts("22,http")
+	assert.NotNil(t, err)
+
+	_, err = parsePorts("70000")
+	assert.NotNil(t, err)
+}
+
+func TestProbeTCPWithListeningPort(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	assert.True(t, probeTCP("127.0.0.1", []int{port}, 100*time.Millisecond))
+}
+
+func TestProbeTCPWithClosedPort(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	// a refused connection (RST) means that the host is alive
+	assert.True(t, probeTCP("127.0.0.1", []int{port}, 100*time.Millisecond))
+}
+
+func TestProbeReportsDevice(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	reported := []model.DetectedInterface{}
+	tracker := ipTracker{
+		probeTimeout: 100 * time.Millisecond,
+		probes:       make(chan struct{}, 1),
+		report: func(itfs []model.DetectedInterface) {
+			reported = append(reported, itfs...)
+		},
+	}
+	d := model.Device{
+		Identifier: "foo",
+		Interfaces: []model.Interface{
+			{Type: model.InterfaceBluetooth, MACAddress: "aa:bb:cc:dd:ee:ff"},
+			{Type: model.InterfaceWifi, IPv4Address: "127.0.0.1"},
+		},
+	}
+
+	// no ports configured, neither globally nor per device
+	tracker.probe(d, []string{"127.0.0.1"})
+	assert.Equal(t, 0, len(reported))
+
+	d.Properties = map[string]string{PropertyProbePorts: strconv.Itoa(port)}
+	tracker.probe(d, []string{"127.0.0.1"})
+	assert.Equal(t, 1, len(reported))
+	assert.Equal(t, "127.0.0.1", reported[0].IPv4Address)
+}
@@ -0,0 +1,95 @@
+package ipv4
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const defaultPingReplyTimeout = 1 * time.Second
+
+// pingRound accounts for the echo requests sent to a single target
+// during a ping (or sweep) operation, and the matching replies.
+type pingRound struct {
+	ip       string
+	sent     int
+	received int
+	rttSum   time.Duration
+}
+
+type pendingEcho struct {
+	round  *pingRound
+	sentAt time.Time
+}
+
+// rounds keeps track of the in-flight echo requests given their sequence number.
+type rounds struct {
+	mutex   sync.Mutex
+	pending map[uint16]pendingEcho
+}
+
+func newRounds() *rounds {
+	return &rounds{pending: make(map[uint16]pendingEcho)}
+}
+
+// sent records that an echo request with the given sequence number was sent,
+// returning false (without recording it) when the sequence number is still in use.
+func (r *rounds) sent(round *pingRound, seq uint16, ts time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, found := r.pending[seq]; found {
+		return false
+	}
+	round.sent++
+	r.pending[seq] = pendingEcho{round: round, sentAt: ts}
+	return true
+}
+
+// received records a reply to an in-flight echo request, returning false when
+// the reply does not match any request (unknown sequence number or address).
+func (r *rounds) received(seq uint16, ip string, ts time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, found := r.pending[seq]
+	if !found || p.round.ip != ip {
+		return false
+	}
+	delete(r.pending, seq)
+	p.round.received++
+	p.round.rttSum += ts.Sub(p.sentAt)
+	return true
+}
+
+// complete forgets about the unanswered requests of a round and returns the
+// detected interface to be reported, if any reply was received.
+func (r *rounds) complete(round *pingRound) (model.DetectedInterface, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for seq, p := range r.pending {
+		if p.round == round {
+			delete(r.pending, seq)
+		}
+	}
+	if round.received == 0 {
+		log.Debugf("No reply from: %s (%d packet(s) lost)", round.ip, round.sent)
+		return model.DetectedInterface{}, false
+	}
+
+	rtt := round.rttSum / time.Duration(round.received)
+	loss := float64(round.sent-round.received) / float64(round.sent)
+	log.Debugf("Got %d/%d reply(ies) from: %s rtt=%s", round.received, round.sent, round.ip, rtt)
+	return model.DetectedInterface{
+		Interface: model.Interface{Type: model.InterfaceUnknown, IPv4Address: round.ip},
+		Data: map[string]string{
+			device.ReportDataLatency:    rtt.Round(time.Microsecond).String(),
+			device.ReportDataPacketLoss: fmt.Sprintf("%.2f", loss),
+		},
+	}, true
+}
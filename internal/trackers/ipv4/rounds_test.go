@@ -0,0 +1,109 @@
+package ipv4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+func TestRoundWithAllReplies(t *testing.T) {
+	r := newRounds()
+	round := &pingRound{ip: "10.0.0.1"}
+	now := time.Now()
+
+	r.sent(round, 1, now)
+	r.sent(round, 2, now.Add(100*time.Millisecond))
+	assert.True(t, r.received(1, "10.0.0.1", now.Add(10*time.Millisecond)))
+	assert.True(t, r.received(2, "10.0.0.1", now.Add(130*time.Millisecond)))
+
+	itf, ok := r.complete(round)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1", itf.IPv4Address)
+	assert.Equal(t, "20ms", itf.Data[device.ReportDataLatency])
+	assert.Equal(t, "0.00", itf.Data[device.ReportDataPacketLoss])
+	assert.Equal(t, 0, len(r.pending))
+}
+
+func TestRoundWithLostPackets(t *testing.T) {
+	r := newRounds()
+	round := &pingRound{ip: "10.0.0.1"}
+	now := time.Now()
+
+	r.sent(round, 1, now)
+	r.sent(round, 2, now)
+	r.sent(round, 3, now)
+	r.sent(round, 4, now)
+	assert.True(t, r.received(3, "10.0.0.1", now.Add(5*time.Millisecond)))
+
+	itf, ok := r.complete(round)
+	assert.True(t, ok)
+	assert.Equal(t, "5ms", itf.Data[device.ReportDataLatency])
+	assert.Equal(t, "0.75", itf.Data[device.ReportDataPacketLoss])
+	assert.Equal(t, 0, len(r.pending))
+}
+
+func TestRoundWithoutReply(t *testing.T) {
+	r := newRounds()
+	round := &pingRound{ip: "10.0.0.1"}
+
+	r.sent(round, 1, time.Now())
+
+	_, ok := r.complete(round)
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(r.pending))
+}
+
+func TestUnexpectedReplies(t *testing.T) {
+	r := newRounds()
+	previous := &pingRound{ip: "10.0.0.1"}
+	latest := &pingRound{ip: "10.0.0.2"}
+	now := time.Now()
+
+	r.sent(previous, 1, now)
+	r.sent(latest, 2, now)
+
+	assert.False(t, r.received(3, "10.0.0.1", now))
+	assert.False(t, r.received(1, "10.0.0.2", now))
+	// a late reply from a previous batch is still accounted for
+	assert.True(t, r.received(1, "10.0.0.1", now))
+	assert.False(t, r.received(1, "10.0.0.1", now))
+}
+
+func TestSequenceNumberInUse(t *testing.T) {
+	r := newRounds()
+	previous := &pingRound{ip: "10.0.0.1"}
+	latest := &pingRound{ip: "10.0.0.2"}
+	now := time.Now()
+
+	assert.True(t, r.sent(previous, 1, now))
+	assert.False(t, r.sent(latest, 1, now))
+	assert.Equal(t, 0, latest.sent)
+	assert.True(t, r.received(1, "10.0.0.1", now))
+	assert.True(t, r.sent(latest, 1, now))
+}
+
+func TestCompleteLater(t *testing.T) {
+	reported := make(chan []model.DetectedInterface, 1)
+	unanswered := make(chan []string, 1)
+	tracker := ipTracker{
+		pingReplyTimeout: time.Millisecond,
+		report:           func(itfs []model.DetectedInterface) { reported <- itfs },
+		rounds:           newRounds(),
+	}
+	answering := &pingRound{ip: "10.0.0.1"}
+	silent := &pingRound{ip: "10.0.0.2"}
+	now := time.Now()
+	tracker.rounds.sent(answering, 1, now)
+	tracker.rounds.sent(silent, 2, now)
+	assert.True(t, tracker.rounds.received(1, "10.0.0.1", now))
+
+	// only the targets that did not answer are probed
+	tracker.completeLater([]*pingRound{answering, silent}, func(ips []string) { unanswered <- ips })
+	itfs := <-reported
+	assert.Equal(t, 1, len(itfs))
+	assert.Equal(t, "10.0.0.1", itfs[0].IPv4Address)
+	assert.Equal(t, []string{"10.0.0.2"}, <-unanswered)
+}
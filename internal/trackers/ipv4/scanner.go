@@ -19,14 +19,24 @@ func (t *ipTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Con
 		return err
 	}
 	t.socket = socket
+	t.report = deviceReport
 
 	stopped := make(chan bool)
 	go func() {
-		t.receiveLoop(deviceReport)
+		t.receiveLoop()
 		stopped <- true
 	}()
 
+	sweepStopped := make(chan bool)
+	go func() {
+		if len(t.sweepRanges) > 0 {
+			t.sweepLoop(ctx)
+		}
+		sweepStopped <- true
+	}()
+
 	<-ctx.Done()
+	<-sweepStopped
 	t.stopReceiving = true
 	t.socket.Close()
 	<-stopped
@@ -0,0 +1,155 @@
+package ipv4
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+const defaultSweepInterval = 1 * time.Hour
+const defaultSweepRate = 20
+const defaultSweepConcurrency = 50
+const maxSweepConcurrency = 1024
+const maxSweepRangeSize = 65536
+
+func (t *ipTracker) sweepLoop(ctx context.Context) {
+	timer := time.NewTimer(10 * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+
+		case <-timer.C:
+			t.sweep(ctx)
+			timer.Reset(t.sweepInterval)
+		}
+	}
+}
+
+// sweep sends an echo request to every address of the configured ranges
+// (except the excluded ones), at the configured rate, then tries to connect
+// to the TCP ports of the ones not answering, if any.
+// Replies are handled by the receive loop and are reported like any other
+// reply, leading to new devices being discovered.
+// The number of addresses being swept at once (i.e. waiting for a reply or
+// being probed) is limited by the sweep concurrency.
+func (t *ipTracker) sweep(ctx context.Context) {
+	targets := sweepTargets(t.sweepRanges, t.sweepExclusions)
+	log.Debugf("Sweeping %d address(es)", len(targets))
+
+	limiter := time.NewTicker(time.Second / time.Duration(t.sweepRate))
+	defer limiter.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, ip := range targets {
+		select {
+		case <-ctx.Done():
+			return
+		case <-limiter.C:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case t.probes <- struct{}{}:
+		}
+
+		round := &pingRound{ip: ip.String()}
+		if err := t.sendEcho(round); errors.Is(err, net.ErrClosed) {
+			<-t.probes
+			return
+		}
+		wg.Add(1)
+		t.completeLater([]*pingRound{round}, func(ips []string) {
+			defer wg.Done()
+			defer func() { <-t.probes }()
+			if len(ips) == 0 || len(t.probePorts) == 0 || t.report == nil {
+				return
+			}
+			if probeTCP(round.ip, t.probePorts, t.probeTimeout) {
+				log.Debug("Got tcp probe answer from: ", round.ip)
+				t.report([]model.DetectedInterface{{Interface: model.Interface{Type: model.InterfaceUnknown, IPv4Address: round.ip}}})
+			}
+		})
+	}
+	log.Debug("Done sweeping")
+}
+
+func sweepTargets(ranges []*net.IPNet, exclusions []*net.IPNet) []net.IP {
+	targets := make([]net.IP, 0)
+	for _, r := range ranges {
+		for _, ip := range hosts(r) {
+			if !contains(exclusions, ip) {
+				targets = append(targets, ip)
+			}
+		}
+	}
+	return targets
+}
+
+// hosts returns the usable host addresses of a network, that is excluding
+// the network and broadcast addresses (unless it is a /31 or /32 network).
+func hosts(n *net.IPNet) []net.IP {
+	ones, bits := n.Mask.Size()
+	size := uint32(1) << uint(bits-ones)
+	first := binary.BigEndian.Uint32(n.IP.To4())
+	last := first + size - 1
+	if size > 2 {
+		first++
+		last--
+	}
+
+	ips := make([]net.IP, 0, size)
+	for v := first; v <= last && v >= first; v++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, v)
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetworks parses a comma separated list of IPv4 addresses and/or
+// networks (in CIDR notation).
+func parseNetworks(v string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0)
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			s = s + "/32"
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		if n.IP.To4() == nil {
+			return nil, fmt.Errorf("not an IPv4 network: %s", s)
+		}
+		ones, bits := n.Mask.Size()
+		if 1<<uint(bits-ones) > maxSweepRangeSize {
+			return nil, fmt.Errorf("network too large: %s", s)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
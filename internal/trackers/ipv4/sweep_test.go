@@ -0,0 +1,70 @@
+package ipv4
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := parseNetworks("192.168.1.0/24, 10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(networks))
+	assert.Equal(t, "192.168.1.0/24", networks[0].String())
+	assert.Equal(t, "10.0.0.1/32", networks[1].String())
+
+	_, err = parseNetworks("foo")
+	assert.NotNil(t, err)
+
+	_, err = parseNetworks("fe80::/64")
+	assert.NotNil(t, err)
+
+	_, err = parseNetworks("10.0.0.0/8")
+	assert.NotNil(t, err)
+}
+
+func TestHosts(t *testing.T) {
+	_, n, _ := net.ParseCIDR("192.168.1.0/30")
+	ips := hosts(n)
+	assert.Equal(t, 2, len(ips))
+	assert.Equal(t, "192.168.1.1", ips[0].String())
+	assert.Equal(t, "192.168.1.2", ips[1].String())
+
+	_, n, _ = net.ParseCIDR("192.168.1.7/32")
+	ips = hosts(n)
+	assert.Equal(t, 1, len(ips))
+	assert.Equal(t, "192.168.1.7", ips[0].String())
+
+	_, n, _ = net.ParseCIDR("192.168.1.0/24")
+	assert.Equal(t, 254, len(hosts(n)))
+}
+
+func TestSweepTargets(t *testing.T) {
+	ranges, _ := parseNetworks("192.168.1.0/29")
+	exclusions, _ := parseNetworks("192.168.1.1,192.168.1.4/31")
+
+	targets := sweepTargets(ranges, exclusions)
+	assert.Equal(t, 3, len(targets))
+	assert.Equal(t, "192.168.1.2", targets[0].String())
+	assert.Equal(t, "192.168.1.3", targets[1].String())
+	assert.Equal(t, "192.168.1.6", targets[2].String())
+}
+
+func TestSweepStopsWhenAllProbing(t *testing.T) {
+	ranges, _ := parseNetworks("192.168.1.1")
+	tracker := ipTracker{
+		probes:      make(chan struct{}, 1),
+		sweepRanges: ranges,
+		sweepRate:   1000,
+	}
+	// all the addresses being swept at once are still awaiting a reply
+	tracker.probes <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	tracker.sweep(ctx)
+	assert.Equal(t, 1, len(tracker.probes))
+}
@@ -1,10 +1,11 @@
 package ipv4
 
 import (
+	"fmt"
+	"net"
 	"strconv"
 	"time"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/touchardv/myhome-presence/internal/config"
 	"github.com/touchardv/myhome-presence/internal/device"
 	"golang.org/x/net/icmp"
@@ -12,23 +13,48 @@ import (
 
 // EnableTracker registers the "ipv4" tracker so that it can be used.
 func EnableTracker() {
-	device.Register("ipv4", newIPTracker)
+	device.Register("ipv4", newIPTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "ping_packet_count", Type: config.TypeInt, Default: strconv.Itoa(defaultPingPacketCount), Description: "The number of ICMP echo requests sent per ping."},
+	{Name: "ping_packet_delay", Type: config.TypeDuration, Default: defaultPingPacketDelay.String(), Description: "The delay between the echo requests."},
+	{Name: "ping_reply_timeout", Type: config.TypeDuration, Default: defaultPingReplyTimeout.String(), Description: "The timeout of the echo replies."},
+	{Name: "tcp_probe_ports", Type: config.TypeString, Description: "The TCP ports probed when a device does not answer pings (e.g. 62078,5353)."},
+	{Name: "tcp_probe_timeout", Type: config.TypeDuration, Default: defaultProbeTimeout.String(), Description: "The timeout of a TCP probe."},
+	{Name: "sweep_ranges", Type: config.TypeString, Description: "The networks (CIDR) swept for discovering devices."},
+	{Name: "sweep_exclude", Type: config.TypeString, Description: "The networks (CIDR) excluded from the sweeps."},
+	{Name: "sweep_interval", Type: config.TypeDuration, Default: defaultSweepInterval.String(), Description: "The interval between sweeps."},
+	{Name: "sweep_rate", Type: config.TypeInt, Default: strconv.Itoa(defaultSweepRate), Description: "The maximum number of addresses swept per second."},
+	{Name: "sweep_concurrency", Type: config.TypeInt, Default: strconv.Itoa(defaultSweepConcurrency), Description: "The maximum number of addresses swept at once, and of concurrent TCP probes of the devices not answering pings."},
 }
 
 type ipTracker struct {
-	pingPacketCount int
-	pingPacketDelay time.Duration
-	sequenceNumber  int32
-	socket          *icmp.PacketConn
-	stopReceiving   bool
+	pingPacketCount  int
+	pingPacketDelay  time.Duration
+	pingReplyTimeout time.Duration
+	probePorts       []int
+	probes           chan struct{}
+	probeTimeout     time.Duration
+	report           device.ReportPresenceFunc
+	rounds           *rounds
+	sequenceNumber   int32
+	socket           *icmp.PacketConn
+	stopReceiving    bool
+
+	sweepConcurrency int
+	sweepExclusions  []*net.IPNet
+	sweepInterval    time.Duration
+	sweepRanges      []*net.IPNet
+	sweepRate        int
 }
 
-func newIPTracker(settings config.Settings) device.Tracker {
+func newIPTracker(settings config.Settings) (device.Tracker, error) {
 	count := defaultPingPacketCount
 	if v, ok := settings["ping_packet_count"]; ok {
 		c, err := strconv.Atoi(v)
 		if err != nil {
-			log.Fatal("Invalid ping_packet_count setting value: ", err)
+			return nil, fmt.Errorf("invalid ping_packet_count setting value: %w", err)
 		}
 		count = c
 	}
@@ -36,14 +62,89 @@ func newIPTracker(settings config.Settings) device.Tracker {
 	if v, ok := settings["ping_packet_delay"]; ok {
 		d, err := time.ParseDuration(v)
 		if err != nil {
-			log.Fatal("Invalid ping_packet_delay setting value: ", err)
+			return nil, fmt.Errorf("invalid ping_packet_delay setting value: %w", err)
 		}
 		delay = d
 	}
-	return &ipTracker{
-		pingPacketCount: count,
-		pingPacketDelay: delay,
-		sequenceNumber:  0,
-		stopReceiving:   false,
+	replyTimeout := defaultPingReplyTimeout
+	if v, ok := settings["ping_reply_timeout"]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ping_reply_timeout setting value: %w", err)
+		}
+		replyTimeout = d
+	}
+	ports := []int{}
+	if v, ok := settings["tcp_probe_ports"]; ok {
+		p, err := parsePorts(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tcp_probe_ports setting value: %w", err)
+		}
+		ports = p
+	}
+	timeout := defaultProbeTimeout
+	if v, ok := settings["tcp_probe_timeout"]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tcp_probe_timeout setting value: %w", err)
+		}
+		timeout = d
+	}
+	t := &ipTracker{
+		pingPacketCount:  count,
+		pingPacketDelay:  delay,
+		pingReplyTimeout: replyTimeout,
+		probePorts:       ports,
+		probeTimeout:     timeout,
+		rounds:           newRounds(),
+		sequenceNumber:   0,
+		stopReceiving:    false,
+		sweepConcurrency: defaultSweepConcurrency,
+		sweepInterval:    defaultSweepInterval,
+		sweepRate:        defaultSweepRate,
+	}
+	if err := t.configureSweep(settings); err != nil {
+		return nil, err
+	}
+	t.probes = make(chan struct{}, t.sweepConcurrency)
+	return t, nil
+}
+
+func (t *ipTracker) configureSweep(settings config.Settings) error {
+	if v, ok := settings["sweep_ranges"]; ok {
+		n, err := parseNetworks(v)
+		if err != nil {
+			return fmt.Errorf("invalid sweep_ranges setting value: %w", err)
+		}
+		t.sweepRanges = n
+	}
+	if v, ok := settings["sweep_exclude"]; ok {
+		n, err := parseNetworks(v)
+		if err != nil {
+			return fmt.Errorf("invalid sweep_exclude setting value: %w", err)
+		}
+		t.sweepExclusions = n
+	}
+	if v, ok := settings["sweep_interval"]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid sweep_interval setting value: %s", v)
+		}
+		t.sweepInterval = d
+	}
+	if v, ok := settings["sweep_rate"]; ok {
+		r, err := strconv.Atoi(v)
+		if err != nil || r <= 0 {
+			return fmt.Errorf("invalid sweep_rate setting value: %s", v)
+		}
+		t.sweepRate = r
+	}
+	if v, ok := settings["sweep_concurrency"]; ok {
+		c, err := strconv.Atoi(v)
+		if err != nil || c <= 0 || c > maxSweepConcurrency {
+			return fmt.Errorf("invalid sweep_concurrency setting value: %s", v)
+		}
+		t.sweepConcurrency = c
 	}
+	return nil
 }
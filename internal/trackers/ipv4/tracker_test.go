@@ -11,7 +11,8 @@ import (
 func TestNew(t *testing.T) {
 	cfg := config.Settings{}
 
-	tr := newIPTracker(cfg)
+	tr, err := newIPTracker(cfg)
+	assert.NoError(t, err)
 	tracker := tr.(*ipTracker)
 	assert.Equal(t, 5, tracker.pingPacketCount)
 	assert.Equal(t, 100*time.Millisecond, tracker.pingPacketDelay)
@@ -19,8 +20,57 @@ func TestNew(t *testing.T) {
 
 	cfg["ping_packet_count"] = "1"
 	cfg["ping_packet_delay"] = "250ms"
-	tr = newIPTracker(cfg)
+	tr, err = newIPTracker(cfg)
+	assert.NoError(t, err)
 	tracker = tr.(*ipTracker)
 	assert.Equal(t, 1, tracker.pingPacketCount)
 	assert.Equal(t, 250*time.Millisecond, tracker.pingPacketDelay)
+
+	cfg["ping_packet_count"] = "many"
+	_, err = newIPTracker(cfg)
+	assert.ErrorContains(t, err, "invalid ping_packet_count setting value")
+}
+
+func TestNewWithTCPProbe(t *testing.T) {
+	cfg := config.Settings{
+		"tcp_probe_ports":   "62078, 445,22",
+		"tcp_probe_timeout": "2s",
+	}
+
+	tr, err := newIPTracker(cfg)
+	assert.NoError(t, err)
+	tracker := tr.(*ipTracker)
+	assert.Equal(t, []int{62078, 445, 22}, tracker.probePorts)
+	assert.Equal(t, 2*time.Second, tracker.probeTimeout)
+}
+
+func TestNewWithSweep(t *testing.T) {
+	cfg := config.Settings{}
+
+	tr, err := newIPTracker(cfg)
+	assert.NoError(t, err)
+	tracker := tr.(*ipTracker)
+	assert.Equal(t, 0, len(tracker.sweepRanges))
+	assert.Equal(t, time.Hour, tracker.sweepInterval)
+	assert.Equal(t, 20, tracker.sweepRate)
+	assert.Equal(t, 50, tracker.sweepConcurrency)
+
+	cfg["sweep_ranges"] = "192.168.1.0/24,192.168.2.0/24"
+	cfg["sweep_exclude"] = "192.168.1.1"
+	cfg["sweep_interval"] = "30m"
+	cfg["sweep_rate"] = "5"
+	cfg["sweep_concurrency"] = "2"
+	tr, err = newIPTracker(cfg)
+	assert.NoError(t, err)
+	tracker = tr.(*ipTracker)
+	assert.Equal(t, 2, len(tracker.sweepRanges))
+	assert.Equal(t, 1, len(tracker.sweepExclusions))
+	assert.Equal(t, 30*time.Minute, tracker.sweepInterval)
+	assert.Equal(t, 5, tracker.sweepRate)
+	assert.Equal(t, 2, tracker.sweepConcurrency)
+	assert.Equal(t, 2, cap(tracker.probes))
+
+	cfg["sweep_concurrency"] = "65536"
+	_, err = newIPTracker(cfg)
+	assert.Error(t, err)
 }
@@ -19,34 +19,43 @@ import (
 
 // EnableTracker registers the "linksys" tracker so that it can be used.
 func EnableTracker() {
-	device.Register("linksys", newLinksysTracker)
+	device.Register("linksys", newLinksysTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "base_url", Type: config.TypeString, Required: true, Description: "The base URL of the router (JNAP) API."},
+	{Name: "auth", Type: config.TypeString, Secret: true, Description: "The authorization header value."},
+	{Name: "sync_interval_minutes", Type: config.TypeInt, Required: true, Description: "The interval (in minutes) between two synchronizations of the devices."},
 }
 
 type linksysTracker struct {
 	auth                string
 	baseURL             string
+	devices             map[string]jnapDevice3
 	lastChangeRevision  int
 	syncIntervalMinutes int
 }
 
-func newLinksysTracker(cfg config.Settings) device.Tracker {
+func newLinksysTracker(cfg config.Settings) (device.Tracker, error) {
 	syncIntervalMinutes, err := strconv.Atoi(cfg["sync_interval_minutes"])
 	if err != nil {
-		log.Fatal("Invalid sync_interval_minutes setting value: ", err)
+		return nil, fmt.Errorf("invalid sync_interval_minutes setting value: %w", err)
 	}
 	return &linksysTracker{
 		auth:                cfg["auth"],
 		baseURL:             cfg["base_url"],
+		devices:             make(map[string]jnapDevice3),
 		lastChangeRevision:  noRevision,
 		syncIntervalMinutes: syncIntervalMinutes,
-	}
+	}, nil
 }
 
 const noRevision = -1
 
 type jnapDeviceConnection struct {
-	IPAddress  string `json:"ipAddress"`
-	MACAddress string `json:"macAddress"`
+	IPAddress      string `json:"ipAddress"`
+	MACAddress     string `json:"macAddress"`
+	ParentDeviceID string `json:"parentDeviceID"`
 }
 
 type jnapDeviceInterface struct {
@@ -54,11 +63,29 @@ type jnapDeviceInterface struct {
 	MACAddress    string `json:"macAddress"`
 }
 
+type jnapDeviceModel struct {
+	DeviceType   string `json:"deviceType"`
+	Manufacturer string `json:"manufacturer"`
+	ModelNumber  string `json:"modelNumber"`
+}
+
+type jnapDeviceProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 type jnapDevice3 struct {
 	Connections        []jnapDeviceConnection `json:"connections"`
 	DeviceID           string                 `json:"deviceID"`
+	FriendlyName       string                 `json:"friendlyName"`
 	KnownInterfaces    []jnapDeviceInterface  `json:"knownInterfaces"`
 	LastChangeRevision int                    `json:"lastChangeRevision"`
+	Model              jnapDeviceModel        `json:"model"`
+	Properties         []jnapDeviceProperty   `json:"properties"`
+}
+
+type jnapDevices3Request struct {
+	SinceRevision int `json:"sinceRevision"`
 }
 
 type jnapDevices3Response struct {
@@ -92,7 +119,13 @@ func (t *linksysTracker) Loop(deviceReport device.ReportPresenceFunc, ctx contex
 
 func (t *linksysTracker) fetchAndReportDevices(deviceReport device.ReportPresenceFunc, _ context.Context) {
 	url := fmt.Sprintf("%s/JNAP/", t.baseURL)
-	req, _ := http.NewRequest("POST", url, strings.NewReader("{}"))
+	body := "{}"
+	if t.lastChangeRevision != noRevision {
+		// only fetch the changes that happened since the last sync
+		b, _ := json.Marshal(jnapDevices3Request{SinceRevision: t.lastChangeRevision})
+		body = string(b)
+	}
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
 	req.Header.Add("Content-Type", "application/json; charset=UTF-8")
 	req.Header.Add("X-Jnap-Action", "http://linksys.com/jnap/devicelist/GetDevices3")
 	req.Header.Add("X-Jnap-Authorization", t.auth)
@@ -122,25 +155,86 @@ func (t *linksysTracker) fetchAndReportDevices(deviceReport device.ReportPresenc
 
 	if response.Result != "OK" {
 		log.Error("Unexpected response result: ", response.Result)
+		// next sync will fetch the full device list
+		t.lastChangeRevision = noRevision
 		return
 	}
-	log.Debugf("Reporting %d device(s)", len(response.Output.Devices))
+	itfs := t.update(response)
+	log.Debugf("Reporting %d device(s)", len(itfs))
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+	t.lastChangeRevision = response.Output.Revision
+}
+
+// update merges the device list changes into the known devices and returns
+// the interfaces to be reported: the connected devices, plus the ones that
+// got disconnected or deleted since the last sync.
+func (t *linksysTracker) update(response jnapDevices3Response) []model.DetectedInterface {
+	if t.devices == nil || t.lastChangeRevision == noRevision {
+		t.devices = make(map[string]jnapDevice3)
+	}
 	itfs := []model.DetectedInterface{}
+
+	for _, id := range response.Output.DeletedDeviceIDs {
+		if d, found := t.devices[id]; found {
+			delete(t.devices, id)
+			if len(d.Connections) > 0 {
+				log.Debugf("Device %s was deleted", id)
+				itfs = append(itfs, model.DetectedInterface{Interface: toInterface(d.Connections[0], d.KnownInterfaces), Departed: true})
+			}
+		}
+	}
 	for i, d := range response.Output.Devices {
 		log.Tracef("response.Output.Devices[%d]=%+v", i, response.Output.Devices[i])
+		previous, found := t.devices[d.DeviceID]
+		t.devices[d.DeviceID] = d
+		if len(d.Connections) == 0 && found && len(previous.Connections) > 0 {
+			log.Debugf("Device %s lost its connection", d.DeviceID)
+			itfs = append(itfs, model.DetectedInterface{Interface: toInterface(previous.Connections[0], previous.KnownInterfaces), Departed: true})
+		}
+	}
+
+	for _, d := range t.devices {
 		if len(d.Connections) == 0 {
 			continue
 		}
 		if len(d.Connections) > 1 {
 			log.Warnf("Found %d connections for %s", len(d.Connections), d.DeviceID)
 		}
-		itfs = append(itfs, model.DetectedInterface{Interface: toInterface(d.Connections[0], d.KnownInterfaces)})
+		itfs = append(itfs, model.DetectedInterface{
+			Interface: toInterface(d.Connections[0], d.KnownInterfaces),
+			Data:      t.toData(d),
+		})
 	}
+	return itfs
+}
 
-	if len(itfs) > 0 {
-		deviceReport(itfs)
+func (t *linksysTracker) toData(d jnapDevice3) map[string]string {
+	data := make(map[string]string)
+	name := d.FriendlyName
+	deviceType := d.Model.DeviceType
+	for _, p := range d.Properties {
+		switch p.Name {
+		case "userDeviceName":
+			name = p.Value
+		case "userDeviceType":
+			deviceType = p.Value
+		}
 	}
-	t.lastChangeRevision = response.Output.Revision
+	device.SetData(data, device.ReportDataSuggestedIdentifier, name)
+	device.SetData(data, device.ReportDataSuggestedDescription, name)
+	device.SetData(data, "DeviceType", deviceType)
+	device.SetData(data, "Manufacturer", d.Model.Manufacturer)
+	device.SetData(data, "Model", d.Model.ModelNumber)
+
+	parentID := d.Connections[0].ParentDeviceID
+	if parent, found := t.devices[parentID]; found && len(parent.FriendlyName) > 0 {
+		device.SetData(data, "ParentNode", parent.FriendlyName)
+	} else {
+		device.SetData(data, "ParentNode", parentID)
+	}
+	return data
 }
 
 func toInterface(conn jnapDeviceConnection, itfs []jnapDeviceInterface) model.Interface {
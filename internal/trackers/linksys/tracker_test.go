@@ -2,6 +2,7 @@ package linksys
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -19,7 +20,8 @@ func TestNew(t *testing.T) {
 		"base_url":              "http://foo",
 		"sync_interval_minutes": "60",
 	}
-	tracker := newLinksysTracker(cfg)
+	tracker, err := newLinksysTracker(cfg)
+	assert.NoError(t, err)
 	linksysTracker := tracker.(*linksysTracker)
 	assert.Equal(t, "XZY", linksysTracker.auth)
 	assert.Equal(t, "http://foo", linksysTracker.baseURL)
@@ -130,17 +132,123 @@ func TestValidHTTPResponseWithDevice(t *testing.T) {
 		Type:        model.InterfaceEthernet,
 		IPv4Address: "192.168.1.2",
 		MACAddress:  "AB:CD:EF:12:34:56",
+	}, Data: map[string]string{
+		"Identifier":  "mydevice",
+		"Description": "mydevice",
+		"DeviceType":  "digital-media-player",
+		"ParentNode":  "c24b3766-1355-4501-b670-ecc0a9411603",
 	}}})
 	tracker := linksysTracker{
-		baseURL: server.URL,
+		baseURL:            server.URL,
+		lastChangeRevision: noRevision,
 	}
 
 	tracker.fetchAndReportDevices(m.report, nil)
 
-	m.AssertNotCalled(t, "report")
+	m.AssertNumberOfCalls(t, "report", 1)
 	assert.Equal(t, 1234, tracker.lastChangeRevision)
 }
 
+func TestIncrementalSync(t *testing.T) {
+	responses := []string{`{
+		"output": {
+			"deletedDeviceIDs": [],
+			"devices": [
+				{
+					"connections": [],
+					"deviceID": "node",
+					"friendlyName": "Living Room Node"
+				},
+				{
+					"connections": [{"ipAddress": "192.168.1.2", "macAddress": "AA:AA:AA:AA:AA:AA", "parentDeviceID": "node"}],
+					"deviceID": "phone",
+					"friendlyName": "John's Phone",
+					"knownInterfaces": [{"interfaceType": "Wireless", "macAddress": "AA:AA:AA:AA:AA:AA"}],
+					"model": {"deviceType": "Mobile", "manufacturer": "Apple", "modelNumber": "iPhone"}
+				},
+				{
+					"connections": [{"ipAddress": "192.168.1.3", "macAddress": "BB:BB:BB:BB:BB:BB"}],
+					"deviceID": "tv",
+					"friendlyName": "TV",
+					"knownInterfaces": [{"interfaceType": "Wired", "macAddress": "BB:BB:BB:BB:BB:BB"}]
+				},
+				{
+					"connections": [{"ipAddress": "192.168.1.4", "macAddress": "CC:CC:CC:CC:CC:CC"}],
+					"deviceID": "laptop",
+					"friendlyName": "Laptop",
+					"knownInterfaces": [{"interfaceType": "Wireless", "macAddress": "CC:CC:CC:CC:CC:CC"}]
+				}
+			],
+			"revision": 10
+		},
+		"result": "OK"
+	}`, `{
+		"output": {
+			"deletedDeviceIDs": ["tv"],
+			"devices": [
+				{
+					"connections": [],
+					"deviceID": "laptop",
+					"friendlyName": "Laptop",
+					"knownInterfaces": [{"interfaceType": "Wireless", "macAddress": "CC:CC:CC:CC:CC:CC"}]
+				}
+			],
+			"revision": 12
+		},
+		"result": "OK"
+	}`}
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		requests = append(requests, string(body))
+		rw.Write([]byte(responses[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+	tracker := linksysTracker{
+		baseURL:            server.URL,
+		lastChangeRevision: noRevision,
+	}
+
+	tracker.fetchAndReportDevices(report, nil)
+	assert.Equal(t, "{}", requests[0])
+	assert.Equal(t, 10, tracker.lastChangeRevision)
+	assert.Equal(t, 3, len(reports[0]))
+	phone := findInterface(reports[0], "AA:AA:AA:AA:AA:AA")
+	assert.False(t, phone.Departed)
+	assert.Equal(t, model.InterfaceWifi, phone.Type)
+	assert.Equal(t, "John's Phone", phone.Data["Identifier"])
+	assert.Equal(t, "John's Phone", phone.Data["Description"])
+	assert.Equal(t, "Mobile", phone.Data["DeviceType"])
+	assert.Equal(t, "Apple", phone.Data["Manufacturer"])
+	assert.Equal(t, "iPhone", phone.Data["Model"])
+	assert.Equal(t, "Living Room Node", phone.Data["ParentNode"])
+
+	tracker.fetchAndReportDevices(report, nil)
+	assert.Equal(t, `{"sinceRevision":10}`, requests[1])
+	assert.Equal(t, 12, tracker.lastChangeRevision)
+	assert.Equal(t, 3, len(reports[1]))
+	phone = findInterface(reports[1], "AA:AA:AA:AA:AA:AA")
+	assert.False(t, phone.Departed) // unchanged => still connected
+	tv := findInterface(reports[1], "BB:BB:BB:BB:BB:BB")
+	assert.True(t, tv.Departed) // deleted
+	laptop := findInterface(reports[1], "CC:CC:CC:CC:CC:CC")
+	assert.True(t, laptop.Departed) // connection lost
+}
+
+func findInterface(itfs []model.DetectedInterface, macAddress string) model.DetectedInterface {
+	for _, itf := range itfs {
+		if itf.MACAddress == macAddress {
+			return itf
+		}
+	}
+	return model.DetectedInterface{}
+}
+
 func mockHTTPServerReturningResponse(t *testing.T, body string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		assert.Equal(t, req.Method, "POST")
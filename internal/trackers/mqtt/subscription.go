@@ -0,0 +1,258 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/internal/jsonpath"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// absentValues are the "present" values reporting that a device has left.
+var absentValues = []string{"false", "0", "off", "offline", "leave", "not_home", "away", "absent"}
+
+// presets provide the default settings of a subscription for well-known presence sources.
+var presets = map[string]map[string]string{
+	"zigbee2mqtt": {
+		"topic":   "zigbee2mqtt/+/availability",
+		"device":  "{{index .Segments 1}}",
+		"present": `{{or (.Path "$.state") .Payload}}`,
+	},
+	"espresense": {
+		"topic":         "espresense/devices/+/+",
+		"device":        "{{index .Segments 2}}",
+		"data.Room":     "{{index .Segments 3}}",
+		"data.Distance": "$.distance",
+		"data.RSSI":     "$.rssi",
+	},
+	"owntracks": {
+		"topic":          "owntracks/+/+",
+		"filter":         `{{or (eq (.Path "$._type") "location") (and (eq (.Path "$._type") "transition") (eq (lower (.Path "$.desc")) "home"))}}`,
+		"device":         "{{index .Segments 1}}-{{index .Segments 2}}",
+		"present":        `{{if eq (.Path "$._type") "transition"}}{{.Path "$.event"}}{{else if contains (.Path "$.inregions") "\"home\""}}home{{else}}not_home{{end}}`,
+		"data.Latitude":  "$.lat",
+		"data.Longitude": "$.lon",
+		"data.Battery":   "$.batt",
+	},
+	"tasmota": {
+		"topic":   "tele/+/LWT",
+		"device":  "{{index .Segments 1}}",
+		"present": "{{.Payload}}",
+	},
+}
+
+var funcs = template.FuncMap{
+	"contains": strings.Contains,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"replace":  strings.ReplaceAll,
+}
+
+// expression extracts a value from a message: either a JSONPath expression
+// (when starting with "$") evaluated against the payload, or a template.
+type expression struct {
+	path     *jsonpath.Path
+	template *template.Template
+}
+
+type subscription struct {
+	name    string
+	topic   string
+	qos     byte
+	filter  *expression
+	device  *expression
+	mac     *expression
+	ip      *expression
+	itfType *expression
+	present *expression
+	data    map[string]*expression
+}
+
+// message is the data available to the expressions.
+type message struct {
+	Topic    string
+	Segments []string
+	Payload  string
+
+	decoded bool
+	json    interface{}
+}
+
+// Path evaluates a JSONPath expression against the (JSON) payload.
+func (m *message) Path(expr string) (string, error) {
+	p, err := jsonpath.Compile(expr)
+	if err != nil {
+		return "", err
+	}
+	return p.First(m.document()), nil
+}
+
+func (m *message) document() interface{} {
+	if !m.decoded {
+		m.decoded = true
+		d := json.NewDecoder(strings.NewReader(m.Payload))
+		d.UseNumber()
+		if err := d.Decode(&m.json); err != nil {
+			m.json = nil
+		}
+	}
+	return m.json
+}
+
+// newSubscription builds a subscription given its settings (with or without a preset).
+func newSubscription(name string, settings map[string]string) (*subscription, error) {
+	if preset, found := settings["preset"]; found {
+		defaults, found := presets[preset]
+		if !found {
+			return nil, fmt.Errorf("unknown preset: %s", preset)
+		}
+		merged := maps.Clone(defaults)
+		maps.Copy(merged, settings)
+		settings = merged
+	}
+
+	s := &subscription{name: name, topic: settings["topic"], data: make(map[string]*expression)}
+	if len(s.topic) == 0 {
+		return nil, fmt.Errorf("missing topic")
+	}
+	switch settings["qos"] {
+	case "", "0":
+	case "1":
+		s.qos = 1
+	case "2":
+		s.qos = 2
+	default:
+		return nil, fmt.Errorf("invalid qos: %s", settings["qos"])
+	}
+
+	for key, v := range settings {
+		switch key {
+		case "preset", "topic", "qos":
+			continue
+		}
+		e, err := newExpression(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		switch key {
+		case "filter":
+			s.filter = e
+		case "device":
+			s.device = e
+		case "mac":
+			s.mac = e
+		case "ip":
+			s.ip = e
+		case "type":
+			s.itfType = e
+		case "present":
+			s.present = e
+		case "identifier":
+			s.data[device.ReportDataSuggestedIdentifier] = e
+		case "description":
+			s.data[device.ReportDataSuggestedDescription] = e
+		default:
+			k, found := strings.CutPrefix(key, "data.")
+			if !found || len(k) == 0 {
+				return nil, fmt.Errorf("unknown setting: %s", key)
+			}
+			s.data[k] = e
+		}
+	}
+	if s.device == nil && s.mac == nil && s.ip == nil {
+		return nil, fmt.Errorf("missing device, mac or ip")
+	}
+	return s, nil
+}
+
+func newExpression(v string) (*expression, error) {
+	if strings.HasPrefix(v, "$") {
+		p, err := jsonpath.Compile(v)
+		if err != nil {
+			return nil, err
+		}
+		return &expression{path: &p}, nil
+	}
+	t, err := template.New("").Funcs(funcs).Option("missingkey=zero").Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return &expression{template: t}, nil
+}
+
+func (e *expression) eval(m *message) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+	if e.path != nil {
+		return strings.TrimSpace(e.path.First(m.document())), nil
+	}
+	var b bytes.Buffer
+	if err := e.template.Execute(&b, m); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// toDetectedInterface maps a received message to a sighting; false is
+// returned when the message is filtered out or does not identify a device.
+func (s *subscription) toDetectedInterface(topic string, payload []byte) (model.DetectedInterface, bool, error) {
+	itf := model.DetectedInterface{}
+	m := &message{Topic: topic, Segments: strings.Split(topic, "/"), Payload: string(payload)}
+
+	var err error
+	eval := func(e *expression) string {
+		if err != nil {
+			return ""
+		}
+		v, evalErr := e.eval(m)
+		err = evalErr
+		return v
+	}
+	if s.filter != nil && eval(s.filter) != "true" {
+		return itf, false, err
+	}
+
+	itf.DeviceID = eval(s.device)
+	itf.MACAddress = eval(s.mac)
+	if hw, err := net.ParseMAC(itf.MACAddress); err == nil {
+		itf.MACAddress = hw.String()
+	}
+	itf.IPv4Address = eval(s.ip)
+	itf.Type = toInterfaceType(eval(s.itfType))
+	if s.present != nil {
+		itf.Departed = slices.Contains(absentValues, strings.ToLower(eval(s.present)))
+	}
+	keys := slices.Sorted(maps.Keys(s.data))
+	for _, k := range keys {
+		if v := eval(s.data[k]); len(v) > 0 {
+			if itf.Data == nil {
+				itf.Data = make(map[string]string)
+			}
+			itf.Data[k] = v
+		}
+	}
+	if err != nil {
+		return itf, false, err
+	}
+	if len(itf.DeviceID) == 0 && len(itf.MACAddress) == 0 && len(itf.IPv4Address) == 0 {
+		return itf, false, nil
+	}
+	return itf, true, nil
+}
+
+func toInterfaceType(v string) model.InterfaceType {
+	for _, t := range []model.InterfaceType{model.InterfaceBluetooth, model.InterfaceEthernet, model.InterfaceWifi} {
+		if strings.EqualFold(v, t.String()) {
+			return t
+		}
+	}
+	return model.InterfaceUnknown
+}
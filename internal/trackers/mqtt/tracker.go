@@ -0,0 +1,151 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "mqtt" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newMQTTTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "hostname", Type: config.TypeString, Required: true, Description: "The host name of the MQTT broker."},
+	{Name: "port", Type: config.TypeInt, Default: strconv.Itoa(defaultPort), Description: "The port of the MQTT broker."},
+	{Name: "username", Type: config.TypeString, Description: "The user name."},
+	{Name: "password", Type: config.TypeString, Secret: true, Description: "The password."},
+	{Name: "subscription.*", Type: config.TypeString, Required: true, Description: "A subscription setting (subscription.<name>.<setting>)."},
+}
+
+const name = "mqtt"
+
+const defaultPort = 1883
+
+type mqttTracker struct {
+	options       *MQTT.ClientOptions
+	subscriptions []*subscription
+	newClient     func(*MQTT.ClientOptions) MQTT.Client
+	report        device.ReportPresenceFunc
+}
+
+func newMQTTTracker(cfg config.Settings) (device.Tracker, error) {
+	server := config.MQTT{Hostname: cfg["hostname"], Port: defaultPort, Username: cfg["username"], Password: cfg["password"]}
+	if len(server.Hostname) == 0 {
+		return nil, errors.New("missing 'hostname' setting")
+	}
+	if v, found := cfg["port"]; found {
+		port, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port setting value: %s", v)
+		}
+		server.Port = uint(port)
+	}
+
+	t := &mqttTracker{
+		options:   device.NewMQTTClientOptions(server, name+"-tracker"),
+		newClient: MQTT.NewClient,
+	}
+	t.options.SetOnConnectHandler(t.subscribe)
+
+	// subscription.<name>.<setting>
+	settings := make(map[string]map[string]string)
+	for k, v := range cfg {
+		if rest, found := strings.CutPrefix(k, "subscription."); found {
+			sub, key, _ := strings.Cut(rest, ".")
+			if settings[sub] == nil {
+				settings[sub] = make(map[string]string)
+			}
+			settings[sub][key] = v
+		}
+	}
+	if len(settings) == 0 {
+		return nil, errors.New("missing 'subscription.<name>.topic' (or preset) setting")
+	}
+	for n, s := range settings {
+		sub, err := newSubscription(n, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscription '%s': %w", n, err)
+		}
+		t.subscriptions = append(t.subscriptions, sub)
+	}
+	sort.Slice(t.subscriptions, func(i, j int) bool {
+		return t.subscriptions[i].name < t.subscriptions[j].name
+	})
+	return t, nil
+}
+
+func (t *mqttTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	t.report = deviceReport
+	client := t.newClient(t.options)
+	retry := time.NewTicker(5 * time.Second)
+
+connectLoop:
+	for {
+		if token := client.Connect(); token.Wait() && token.Error() != nil {
+			log.Errorf("[%s] Failed to connect: %s", name, token.Error())
+		} else {
+			break connectLoop
+		}
+
+		select {
+		case <-retry.C:
+			continue
+
+		case <-ctx.Done():
+			break connectLoop
+		}
+	}
+	retry.Stop()
+
+	<-ctx.Done()
+	if client.IsConnected() {
+		client.Disconnect(250)
+	}
+	log.Infof("Stopped: %s tracker", name)
+	return nil
+}
+
+// subscribe (re-)subscribes to all topics, once connected.
+func (t *mqttTracker) subscribe(client MQTT.Client) {
+	for _, s := range t.subscriptions {
+		token := client.Subscribe(s.topic, s.qos, func(c MQTT.Client, m MQTT.Message) {
+			t.handle(s, m)
+		})
+		if token.Wait() && token.Error() != nil {
+			log.Errorf("[%s] Failed to subscribe to '%s': %s", name, s.topic, token.Error())
+			continue
+		}
+		log.Debugf("[%s] Subscribed to '%s'", name, s.topic)
+	}
+}
+
+func (t *mqttTracker) handle(s *subscription, m MQTT.Message) {
+	itf, ok, err := s.toDetectedInterface(m.Topic(), m.Payload())
+	if err != nil {
+		log.Warnf("[%s] Failed to handle message from '%s': %s", name, m.Topic(), err)
+		return
+	}
+	if ok && t.report != nil {
+		t.report([]model.DetectedInterface{itf})
+	}
+}
+
+func (t *mqttTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
@@ -0,0 +1,290 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// broker is a stand-in for an MQTT broker (and client), delivering the
+// published messages to the matching subscriptions.
+type broker struct {
+	MQTT.Client
+	opts          *MQTT.ClientOptions
+	mutex         sync.Mutex
+	connected     bool
+	subscriptions map[string]MQTT.MessageHandler
+}
+
+type token struct {
+	MQTT.Token
+	err error
+}
+
+func (t *token) Wait() bool   { return true }
+func (t *token) Error() error { return t.err }
+
+type fakeMessage struct {
+	MQTT.Message
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string   { return m.topic }
+func (m *fakeMessage) Payload() []byte { return m.payload }
+
+func (b *broker) Connect() MQTT.Token {
+	b.mutex.Lock()
+	b.connected = true
+	b.mutex.Unlock()
+	b.opts.OnConnect(b)
+	return &token{}
+}
+
+func (b *broker) IsConnected() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.connected
+}
+
+func (b *broker) Disconnect(quiesce uint) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.connected = false
+}
+
+func (b *broker) Subscribe(topic string, qos byte, callback MQTT.MessageHandler) MQTT.Token {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[string]MQTT.MessageHandler)
+	}
+	b.subscriptions[topic] = callback
+	return &token{}
+}
+
+func (b *broker) publish(topic string, payload string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for filter, callback := range b.subscriptions {
+		if matches(filter, topic) {
+			callback(b, &fakeMessage{topic: topic, payload: []byte(payload)})
+		}
+	}
+}
+
+func matches(filter string, topic string) bool {
+	f, t := splitTopic(filter), splitTopic(topic)
+	for i := range f {
+		if f[i] == "#" {
+			return true
+		}
+		if i >= len(t) || (f[i] != "+" && f[i] != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+func splitTopic(s string) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == '/' {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return parts
+}
+
+func startTracker(t *testing.T, settings config.Settings) (*broker, *[]model.DetectedInterface, context.CancelFunc, *sync.WaitGroup) {
+	tr, err := newMQTTTracker(settings)
+	assert.NoError(t, err)
+	tracker := tr.(*mqttTracker)
+	b := &broker{}
+	tracker.newClient = func(opts *MQTT.ClientOptions) MQTT.Client {
+		b.opts = opts
+		return b
+	}
+
+	mutex := sync.Mutex{}
+	reported := []model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reported = append(reported, itfs...)
+	}
+
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go tracker.Loop(report, ctx, wg)
+	assert.Eventually(t, b.IsConnected, time.Second, time.Millisecond)
+	return b, &reported, cancel, wg
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newMQTTTracker(config.Settings{
+		"hostname":                   "192.10.20.1",
+		"port":                       "1884",
+		"username":                   "foo",
+		"password":                   "bar",
+		"subscription.z2m.preset":    "zigbee2mqtt",
+		"subscription.phones.topic":  "home/phones/+",
+		"subscription.phones.mac":    "$.mac",
+		"subscription.phones.qos":    "1",
+		"subscription.phones.data.X": "{{.Payload}}",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*mqttTracker)
+
+	assert.Equal(t, "tcp://192.10.20.1:1884", tracker.options.Servers[0].String())
+	assert.Equal(t, "foo", tracker.options.Username)
+	assert.Contains(t, tracker.options.ClientID, "-mqtt-tracker")
+	assert.Equal(t, 2, len(tracker.subscriptions))
+	assert.Equal(t, "home/phones/+", tracker.subscriptions[0].topic)
+	assert.Equal(t, byte(1), tracker.subscriptions[0].qos)
+	assert.Equal(t, "zigbee2mqtt/+/availability", tracker.subscriptions[1].topic)
+
+	_, err = newMQTTTracker(config.Settings{"hostname": "192.10.20.1", "subscription.z2m.preset": "unknown"})
+	assert.EqualError(t, err, "invalid subscription 'z2m': unknown preset: unknown")
+}
+
+func TestNewSubscriptionErrors(t *testing.T) {
+	_, err := newSubscription("foo", map[string]string{"preset": "unknown"})
+	assert.EqualError(t, err, "unknown preset: unknown")
+
+	_, err = newSubscription("foo", map[string]string{"mac": "$.mac"})
+	assert.EqualError(t, err, "missing topic")
+
+	_, err = newSubscription("foo", map[string]string{"topic": "foo"})
+	assert.EqualError(t, err, "missing device, mac or ip")
+
+	_, err = newSubscription("foo", map[string]string{"topic": "foo", "mac": "{{.Payload"})
+	assert.ErrorContains(t, err, "invalid mac")
+
+	_, err = newSubscription("foo", map[string]string{"topic": "foo", "mac": "$.mac", "colour": "red"})
+	assert.EqualError(t, err, "unknown setting: colour")
+}
+
+func TestPresets(t *testing.T) {
+	b, reported, cancel, wg := startTracker(t, config.Settings{
+		"hostname":                        "localhost",
+		"subscription.z2m.preset":         "zigbee2mqtt",
+		"subscription.espresense.preset":  "espresense",
+		"subscription.owntracks.preset":   "owntracks",
+		"subscription.tasmota.preset":     "tasmota",
+		"subscription.tasmota.identifier": "{{index .Segments 1}}",
+	})
+
+	b.publish("zigbee2mqtt/door-sensor/availability", `{"state": "online"}`)
+	b.publish("zigbee2mqtt/motion-sensor/availability", `offline`)
+	b.publish("espresense/devices/phone/kitchen", `{"id": "phone", "rssi": -62, "distance": 1.25}`)
+	b.publish("owntracks/alice/phone", `{"_type": "location", "lat": 50.85, "lon": 4.35, "batt": 87, "inregions": ["home"]}`)
+	b.publish("owntracks/alice/phone", `{"_type": "transition", "event": "leave", "desc": "Home"}`)
+	b.publish("owntracks/alice/phone", `{"_type": "transition", "event": "enter", "desc": "Work"}`)
+	b.publish("owntracks/alice/phone", `{"_type": "lwt"}`)
+	b.publish("tele/plug/LWT", `Offline`)
+	b.publish("home/unrelated", `Online`)
+
+	cancel()
+	wg.Wait()
+	assert.False(t, b.IsConnected())
+
+	assert.Equal(t, []model.DetectedInterface{
+		{DeviceID: "door-sensor"},
+		{DeviceID: "motion-sensor", Departed: true},
+		{DeviceID: "phone", Data: map[string]string{"Distance": "1.25", "RSSI": "-62", "Room": "kitchen"}},
+		{DeviceID: "alice-phone", Data: map[string]string{"Battery": "87", "Latitude": "50.85", "Longitude": "4.35"}},
+		{DeviceID: "alice-phone", Departed: true},
+		{DeviceID: "plug", Departed: true, Data: map[string]string{"Identifier": "plug"}},
+	}, *reported)
+}
+
+func TestCustomSubscription(t *testing.T) {
+	s, err := newSubscription("leases", map[string]string{
+		"topic":       "dhcp/+/lease",
+		"mac":         "$.hwaddr",
+		"ip":          "$.address",
+		"type":        `{{if eq (.Path "$.iface") "wlan0"}}wifi{{else}}ethernet{{end}}`,
+		"present":     `{{if eq (.Path "$.event") "expired"}}absent{{else}}present{{end}}`,
+		"description": "{{upper (.Path \"$.hostname\")}} on {{index .Segments 1}}",
+	})
+	assert.Nil(t, err)
+
+	itf, ok, err := s.toDetectedInterface("dhcp/router/lease", []byte(`{"hwaddr": "AA-BB-CC-DD-EE-FF", "address": "192.10.20.30", "iface": "wlan0", "hostname": "phone", "event": "added"}`))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, model.DetectedInterface{
+		Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:ff", IPv4Address: "192.10.20.30"},
+		Data:      map[string]string{"Description": "PHONE on router"},
+	}, itf)
+
+	itf, ok, err = s.toDetectedInterface("dhcp/router/lease", []byte(`{"hwaddr": "AA-BB-CC-DD-EE-FF", "iface": "eth0", "event": "expired"}`))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, itf.Departed)
+	assert.Equal(t, model.InterfaceEthernet, itf.Type)
+
+	_, ok, err = s.toDetectedInterface("dhcp/router/lease", []byte(`not json`))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	s, _ = newSubscription("broken", map[string]string{"topic": "foo/+", "device": "{{index .Segments 5}}"})
+	_, ok, err = s.toDetectedInterface("foo/bar", []byte(`{}`))
+	assert.NotNil(t, err)
+	assert.False(t, ok)
+}
+
+func TestLoopWithUnreachableBroker(t *testing.T) {
+	tr, err := newMQTTTracker(config.Settings{
+		"hostname":                "localhost",
+		"subscription.z2m.preset": "zigbee2mqtt",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*mqttTracker)
+	b := &unreachableBroker{}
+	tracker.newClient = func(opts *MQTT.ClientOptions) MQTT.Client {
+		return b
+	}
+
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go tracker.Loop(nil, ctx, wg)
+
+	assert.Eventually(t, func() bool { return b.attempts() > 0 }, time.Second, time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+type unreachableBroker struct {
+	MQTT.Client
+	mutex sync.Mutex
+	count int
+}
+
+func (b *unreachableBroker) Connect() MQTT.Token {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.count++
+	return &token{err: errors.New("connection refused")}
+}
+
+func (b *unreachableBroker) IsConnected() bool {
+	return false
+}
+
+func (b *unreachableBroker) attempts() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.count
+}
@@ -0,0 +1,163 @@
+package openwrt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "openwrt" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newOpenWrtTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "url", Type: config.TypeString, Required: true, Description: "The URL of the access point (ubus) API."},
+	{Name: "name", Type: config.TypeString, Description: "The name of the access point (the URL host name by default)."},
+	{Name: "username", Type: config.TypeString, Required: true, Description: "The user name."},
+	{Name: "password", Type: config.TypeString, Required: true, Secret: true, Description: "The password."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the stations."},
+}
+
+const name = "openwrt"
+
+const defaultPollInterval = 1 * time.Minute
+
+type loginFunc func(baseURL string, username string, password string) (session, error)
+
+type statusFunc func(baseURL string, s session) ([]station, error)
+
+type openwrtTracker struct {
+	accessPoint  string
+	baseURL      string
+	username     string
+	password     string
+	pollInterval time.Duration
+	login        loginFunc
+	status       statusFunc
+	session      *session
+}
+
+func newOpenWrtTracker(cfg config.Settings) (device.Tracker, error) {
+	if err := schema.Missing(cfg); err != nil {
+		return nil, err
+	}
+	baseURL := cfg["url"]
+	accessPoint := cfg["name"]
+	if len(accessPoint) == 0 {
+		if u, err := url.Parse(baseURL); err == nil {
+			accessPoint = u.Hostname()
+		}
+	}
+	interval, err := schema.Duration(cfg, "poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	return &openwrtTracker{
+		accessPoint:  accessPoint,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		username:     cfg["username"],
+		password:     cfg["password"],
+		pollInterval: interval,
+		login:        ubusLogin,
+		status:       ubusStatus,
+	}, nil
+}
+
+func (t *openwrtTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	ticker := time.NewTicker(1 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopped: %s tracker", name)
+			return nil
+
+		case <-ticker.C:
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
+		}
+	}
+}
+
+func (t *openwrtTracker) poll(deviceReport device.ReportPresenceFunc) {
+	stations, err := t.fetchStations()
+	if err != nil {
+		log.Errorf("[%s] %s", name, err)
+		return
+	}
+	log.Debugf("[%s] detected %d wireless station(s)", name, len(stations))
+	itfs := []model.DetectedInterface{}
+	for _, s := range stations {
+		itfs = append(itfs, t.toDetectedInterface(s))
+	}
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+}
+
+// fetchStations retrieves the associated stations, renewing the session
+// when it is about to expire or when it got rejected by the router.
+func (t *openwrtTracker) fetchStations() ([]station, error) {
+	if t.session != nil && time.Now().Add(t.pollInterval).Before(t.session.ExpiresAt) {
+		stations, err := t.status(t.baseURL, *t.session)
+		if err == nil {
+			return stations, nil
+		}
+		if !errors.Is(err, errPermissionDenied) {
+			return nil, fmt.Errorf("status failed: %w", err)
+		}
+		log.Debugf("[%s] session was rejected", name)
+	}
+	t.session = nil
+
+	s, err := t.login(t.baseURL, t.username, t.password)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	t.session = &s
+	stations, err := t.status(t.baseURL, s)
+	if err != nil {
+		return nil, fmt.Errorf("status failed: %w", err)
+	}
+	return stations, nil
+}
+
+func (t *openwrtTracker) toDetectedInterface(s station) model.DetectedInterface {
+	data := map[string]string{
+		"AccessPoint": t.accessPoint,
+		"Radio":       s.Radio,
+		"Signal":      strconv.Itoa(s.Signal),
+	}
+	hostname := strings.TrimSpace(s.Hostname)
+	if len(hostname) > 0 {
+		data[device.ReportDataSuggestedIdentifier] = hostname
+		data[device.ReportDataSuggestedDescription] = hostname
+	}
+	return model.DetectedInterface{
+		Interface: model.Interface{
+			Type:        model.InterfaceWifi,
+			MACAddress:  s.MACAddress,
+			IPv4Address: s.IPv4Address,
+		},
+		Data: data,
+	}
+}
+
+func (t *openwrtTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
@@ -0,0 +1,194 @@
+package openwrt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// ubusServer is a stand-in for the OpenWrt ubus JSON-RPC endpoint.
+type ubusServer struct {
+	t        *testing.T
+	logins   int
+	sessions map[string]bool
+}
+
+func newUbusServer(t *testing.T) *httptest.Server {
+	s := &ubusServer{t: t, sessions: make(map[string]bool)}
+	return httptest.NewServer(s)
+}
+
+func (s *ubusServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	assert.Equal(s.t, "POST", req.Method)
+	assert.Equal(s.t, "/ubus", req.URL.Path)
+
+	r := ubusRequest{}
+	json.NewDecoder(req.Body).Decode(&r)
+	sessionID := r.Params[0].(string)
+	if sessionID != noSession && !s.sessions[sessionID] {
+		rw.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Access denied"}}`))
+		return
+	}
+
+	var result string
+	switch r.Method {
+	case "list":
+		result = `{"hostapd.wlan0": {"get_clients": {}}, "hostapd.wlan1": {"get_clients": {}}}`
+	case "call":
+		object := r.Params[1].(string) + "." + r.Params[2].(string)
+		switch object {
+		case "session.login":
+			args := r.Params[3].(map[string]interface{})
+			if args["username"] != "root" || args["password"] != "secret" {
+				result = `[6]`
+				break
+			}
+			s.logins++
+			id := "session-" + string(rune('0'+s.logins))
+			s.sessions[id] = true
+			result = `[0, {"ubus_rpc_session": "` + id + `", "timeout": 300, "expires": 300}]`
+		case "hostapd.wlan0.get_clients":
+			result = `[0, {"freq": 2412, "clients": {
+				"aa:bb:cc:dd:ee:01": {"authorized": true, "signal": -48},
+				"aa:bb:cc:dd:ee:02": {"authorized": false, "signal": -80}
+			}}]`
+		case "hostapd.wlan1.get_clients":
+			result = `[0, {"freq": 5180, "clients": {
+				"aa:bb:cc:dd:ee:03": {"authorized": true, "signal": -61}
+			}}]`
+		case "luci-rpc.getDHCPLeases":
+			result = `[0, {"dhcp_leases": [
+				{"expires": 3600, "hostname": "phone", "ipaddr": "192.168.1.20", "macaddr": "AA:BB:CC:DD:EE:01"}
+			]}]`
+		default:
+			result = `[4]`
+		}
+	}
+	rw.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.Settings{
+		"url":      "http://192.168.1.1/",
+		"username": "root",
+		"password": "secret",
+	}
+	tr, err := newOpenWrtTracker(cfg)
+	assert.NoError(t, err)
+	tracker := tr.(*openwrtTracker)
+	assert.Equal(t, "http://192.168.1.1", tracker.baseURL)
+	assert.Equal(t, "192.168.1.1", tracker.accessPoint)
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+
+	cfg["name"] = "living-room-ap"
+	cfg["poll_interval"] = "30s"
+	tr, err = newOpenWrtTracker(cfg)
+	assert.NoError(t, err)
+	tracker = tr.(*openwrtTracker)
+	assert.Equal(t, "living-room-ap", tracker.accessPoint)
+	assert.Equal(t, 30*time.Second, tracker.pollInterval)
+
+	delete(cfg, "password")
+	_, err = newOpenWrtTracker(cfg)
+	assert.EqualError(t, err, "missing 'password' setting")
+}
+
+func TestLoop(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := openwrtTracker{}
+
+	go tracker.Loop(nil, ctx, wg)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestPoll(t *testing.T) {
+	server := newUbusServer(t)
+	defer server.Close()
+
+	tr, err := newOpenWrtTracker(config.Settings{
+		"url":      server.URL,
+		"name":     "ap",
+		"username": "root",
+		"password": "secret",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*openwrtTracker)
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:01", IPv4Address: "192.168.1.20"},
+			Data: map[string]string{
+				"AccessPoint": "ap",
+				"Description": "phone",
+				"Identifier":  "phone",
+				"Radio":       "wlan0",
+				"Signal":      "-48",
+			},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:03"},
+			Data: map[string]string{
+				"AccessPoint": "ap",
+				"Radio":       "wlan1",
+				"Signal":      "-61",
+			},
+		},
+	}, reports[0])
+	assert.Equal(t, "session-1", tracker.session.ID)
+
+	// the session is reused
+	tracker.poll(report)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, "session-1", tracker.session.ID)
+
+	// the session is renewed when rejected by the router
+	tracker.session.ID = "expired"
+	tracker.poll(report)
+	assert.Equal(t, 3, len(reports))
+	assert.Equal(t, "session-2", tracker.session.ID)
+
+	// the session is renewed when about to expire
+	tracker.session.ExpiresAt = time.Now()
+	tracker.poll(report)
+	assert.Equal(t, 4, len(reports))
+	assert.Equal(t, "session-3", tracker.session.ID)
+}
+
+func TestPollWithInvalidCredentials(t *testing.T) {
+	server := newUbusServer(t)
+	defer server.Close()
+
+	tr, err := newOpenWrtTracker(config.Settings{
+		"url":      server.URL,
+		"username": "root",
+		"password": "wrong",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*openwrtTracker)
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+	assert.Nil(t, tracker.session)
+}
@@ -0,0 +1,205 @@
+package openwrt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const noSession = "00000000000000000000000000000000"
+
+const (
+	ubusStatusOK               = 0
+	ubusStatusPermissionDenied = 6
+)
+
+var errPermissionDenied = errors.New("permission denied")
+
+type ubusRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type ubusResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type session struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+type station struct {
+	MACAddress  string
+	IPv4Address string
+	Hostname    string
+	Radio       string
+	Signal      int
+}
+
+type loginResult struct {
+	Session string `json:"ubus_rpc_session"`
+	Expires int    `json:"expires"`
+}
+
+type getClientsResult struct {
+	Clients map[string]struct {
+		Authorized bool `json:"authorized"`
+		Signal     int  `json:"signal"`
+	} `json:"clients"`
+}
+
+type dhcpLease struct {
+	Hostname   string `json:"hostname"`
+	IPAddress  string `json:"ipaddr"`
+	MACAddress string `json:"macaddr"`
+}
+
+type getDHCPLeasesResult struct {
+	Leases []dhcpLease `json:"dhcp_leases"`
+}
+
+func ubusLogin(baseURL string, username string, password string) (session, error) {
+	s := session{}
+	result := loginResult{}
+	err := ubusCall(baseURL, noSession, "session", "login", map[string]string{
+		"username": username,
+		"password": password,
+	}, &result)
+	if err != nil {
+		return s, err
+	}
+	if len(result.Session) == 0 {
+		return s, errors.New("missing session identifier")
+	}
+	s.ID = result.Session
+	s.ExpiresAt = time.Now().Add(time.Duration(result.Expires) * time.Second)
+	return s, nil
+}
+
+func ubusStatus(baseURL string, s session) ([]station, error) {
+	objects := map[string]json.RawMessage{}
+	if err := ubusList(baseURL, s.ID, "hostapd.*", &objects); err != nil {
+		return nil, err
+	}
+
+	stations := []station{}
+	for object := range objects {
+		result := getClientsResult{}
+		if err := ubusCall(baseURL, s.ID, object, "get_clients", map[string]string{}, &result); err != nil {
+			return nil, fmt.Errorf("%s: %w", object, err)
+		}
+		for mac, c := range result.Clients {
+			if !c.Authorized {
+				continue
+			}
+			stations = append(stations, station{
+				MACAddress: mac,
+				Radio:      strings.TrimPrefix(object, "hostapd."),
+				Signal:     c.Signal,
+			})
+		}
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].MACAddress < stations[j].MACAddress
+	})
+
+	leases := getDHCPLeasesResult{}
+	err := ubusCall(baseURL, s.ID, "luci-rpc", "getDHCPLeases", map[string]string{}, &leases)
+	if err != nil {
+		// the luci-rpc object is optional (e.g. on a dumb access point)
+		return stations, nil
+	}
+	for i := range stations {
+		for _, l := range leases.Leases {
+			if strings.EqualFold(l.MACAddress, stations[i].MACAddress) {
+				stations[i].Hostname = l.Hostname
+				stations[i].IPv4Address = l.IPAddress
+				break
+			}
+		}
+	}
+	return stations, nil
+}
+
+func ubusList(baseURL string, sessionID string, pattern string, out interface{}) error {
+	response, err := ubusPost(baseURL, "list", []interface{}{sessionID, pattern})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response.Result, out)
+}
+
+func ubusCall(baseURL string, sessionID string, object string, method string, args interface{}, out interface{}) error {
+	response, err := ubusPost(baseURL, "call", []interface{}{sessionID, object, method, args})
+	if err != nil {
+		return err
+	}
+
+	// the result of a call is an array: [status code, optional data]
+	result := []json.RawMessage{}
+	if err = json.Unmarshal(response.Result, &result); err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return errors.New("empty ubus result")
+	}
+	var code int
+	if err = json.Unmarshal(result[0], &code); err != nil {
+		return err
+	}
+	switch code {
+	case ubusStatusOK:
+	case ubusStatusPermissionDenied:
+		return errPermissionDenied
+	default:
+		return fmt.Errorf("ubus status code: %d", code)
+	}
+	if len(result) < 2 {
+		return nil
+	}
+	return json.Unmarshal(result[1], out)
+}
+
+func ubusPost(baseURL string, method string, params []interface{}) (ubusResponse, error) {
+	response := ubusResponse{}
+	body, _ := json.Marshal(ubusRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+
+	url := fmt.Sprintf("%s/ubus", baseURL)
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return response, errors.New("unexpected http response " + res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return response, err
+	}
+	if response.Error != nil {
+		if response.Error.Code == -32002 {
+			// "Access denied": the session is unknown or has expired
+			return response, errPermissionDenied
+		}
+		return response, errors.New(response.Error.Message)
+	}
+	return response, nil
+}
@@ -0,0 +1,210 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	oidIfName                     = ".1.3.6.1.2.1.31.1.1.1.1"
+	oidIfDescr                    = ".1.3.6.1.2.1.2.2.1.2"
+	oidDot1dBasePortIfIndex       = ".1.3.6.1.2.1.17.1.4.1.2"
+	oidDot1dTpFdbPort             = ".1.3.6.1.2.1.17.4.3.1.2"
+	oidDot1qTpFdbPort             = ".1.3.6.1.2.1.17.7.1.2.2.1.2"
+	oidIPNetToPhysicalPhysAddress = ".1.3.6.1.2.1.4.35.1.2"
+	oidIPNetToMediaPhysAddress    = ".1.3.6.1.2.1.4.22.1.2"
+)
+
+// walker retrieves all the variables of a subtree of an SNMP agent.
+type walker interface {
+	walk(oid string) ([]gosnmp.SnmpPDU, error)
+	close()
+}
+
+// fdbEntry is a MAC address learned by a switch on one of its ports.
+type fdbEntry struct {
+	MACAddress string
+	Port       string
+	VLAN       int
+}
+
+// neighbor is an entry of the ARP table of a switch or router.
+type neighbor struct {
+	MACAddress  string
+	IPv4Address string
+}
+
+// forwardingEntries returns the content of the Q-BRIDGE-MIB forwarding table,
+// or of the BRIDGE-MIB one when the agent does not support VLANs.
+func forwardingEntries(w walker) ([]fdbEntry, error) {
+	ports, err := bridgePorts(w)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []fdbEntry{}
+	pdus, err := w.walk(oidDot1qTpFdbPort)
+	if err == nil && len(pdus) > 0 {
+		// index: dot1qFdbId (VLAN) + MAC address
+		for _, pdu := range pdus {
+			index, ok := suffix(pdu.Name, oidDot1qTpFdbPort)
+			if !ok || len(index) != 7 {
+				continue
+			}
+			if e, ok := toFdbEntry(index[1:], pdu, ports); ok {
+				e.VLAN = index[0]
+				entries = append(entries, e)
+			}
+		}
+		return entries, nil
+	}
+
+	pdus, err = w.walk(oidDot1dTpFdbPort)
+	if err != nil {
+		return nil, err
+	}
+	// index: MAC address
+	for _, pdu := range pdus {
+		index, ok := suffix(pdu.Name, oidDot1dTpFdbPort)
+		if !ok || len(index) != 6 {
+			continue
+		}
+		if e, ok := toFdbEntry(index, pdu, ports); ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func toFdbEntry(mac []int, pdu gosnmp.SnmpPDU, ports map[int]string) (fdbEntry, bool) {
+	port := int(gosnmp.ToBigInt(pdu.Value).Int64())
+	if port == 0 {
+		// the address belongs to the switch itself
+		return fdbEntry{}, false
+	}
+	name, found := ports[port]
+	if !found {
+		name = strconv.Itoa(port)
+	}
+	hw := make(net.HardwareAddr, len(mac))
+	for i, b := range mac {
+		hw[i] = byte(b)
+	}
+	return fdbEntry{MACAddress: hw.String(), Port: name}, true
+}
+
+// bridgePorts maps the bridge port numbers to the name of their interface.
+func bridgePorts(w walker) (map[int]string, error) {
+	names, err := interfaceNames(w)
+	if err != nil {
+		return nil, err
+	}
+	pdus, err := w.walk(oidDot1dBasePortIfIndex)
+	if err != nil {
+		return nil, err
+	}
+	ports := make(map[int]string)
+	for _, pdu := range pdus {
+		index, ok := suffix(pdu.Name, oidDot1dBasePortIfIndex)
+		if !ok || len(index) != 1 {
+			continue
+		}
+		ifIndex := int(gosnmp.ToBigInt(pdu.Value).Int64())
+		if name, found := names[ifIndex]; found {
+			ports[index[0]] = name
+		}
+	}
+	return ports, nil
+}
+
+func interfaceNames(w walker) (map[int]string, error) {
+	oid := oidIfName
+	pdus, err := w.walk(oid)
+	if err != nil || len(pdus) == 0 {
+		oid = oidIfDescr
+		if pdus, err = w.walk(oid); err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[int]string)
+	for _, pdu := range pdus {
+		index, ok := suffix(pdu.Name, oid)
+		if !ok || len(index) != 1 {
+			continue
+		}
+		if b, ok := pdu.Value.([]byte); ok {
+			names[index[0]] = strings.TrimSpace(string(b))
+		}
+	}
+	return names, nil
+}
+
+// neighbors returns the IPv4 entries of the IP-MIB ipNetToPhysicalTable,
+// or of the deprecated ipNetToMediaTable when the former is not supported.
+func neighbors(w walker) ([]neighbor, error) {
+	result := []neighbor{}
+	pdus, err := w.walk(oidIPNetToPhysicalPhysAddress)
+	if err == nil && len(pdus) > 0 {
+		// index: ifIndex + address type + address length + address
+		for _, pdu := range pdus {
+			index, ok := suffix(pdu.Name, oidIPNetToPhysicalPhysAddress)
+			if !ok || len(index) != 7 || index[1] != 1 || index[2] != 4 {
+				continue
+			}
+			if n, ok := toNeighbor(index[3:], pdu); ok {
+				result = append(result, n)
+			}
+		}
+		return result, nil
+	}
+
+	pdus, err = w.walk(oidIPNetToMediaPhysAddress)
+	if err != nil {
+		return nil, err
+	}
+	// index: ifIndex + address
+	for _, pdu := range pdus {
+		index, ok := suffix(pdu.Name, oidIPNetToMediaPhysAddress)
+		if !ok || len(index) != 5 {
+			continue
+		}
+		if n, ok := toNeighbor(index[1:], pdu); ok {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func toNeighbor(ip []int, pdu gosnmp.SnmpPDU) (neighbor, bool) {
+	b, ok := pdu.Value.([]byte)
+	if !ok || len(b) != 6 {
+		return neighbor{}, false
+	}
+	return neighbor{
+		MACAddress:  net.HardwareAddr(b).String(),
+		IPv4Address: fmt.Sprintf("%d.%d.%d.%d", ip[0], ip[1], ip[2], ip[3]),
+	}, true
+}
+
+// suffix returns the index part of a variable name (i.e. after the table OID).
+func suffix(name string, oid string) ([]int, bool) {
+	name = strings.TrimPrefix(name, ".")
+	oid = strings.TrimPrefix(oid, ".")
+	if !strings.HasPrefix(name, oid+".") {
+		return nil, false
+	}
+	parts := strings.Split(name[len(oid)+1:], ".")
+	index := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		index[i] = v
+	}
+	return index, true
+}
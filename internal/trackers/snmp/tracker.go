@@ -0,0 +1,295 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "snmp" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newSNMPTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "targets", Type: config.TypeString, Required: true, Description: "The (comma separated) SNMP agents, as host[:port]."},
+	{Name: "version", Type: config.TypeString, Default: "2c", Description: "The SNMP version (1, 2c or 3)."},
+	{Name: "community", Type: config.TypeString, Default: defaultCommunity, Secret: true, Description: "The community (versions 1 and 2c)."},
+	{Name: "username", Type: config.TypeString, Description: "The user name (version 3)."},
+	{Name: "auth_protocol", Type: config.TypeString, Description: "The authentication protocol (version 3)."},
+	{Name: "auth_password", Type: config.TypeString, Secret: true, Description: "The authentication password (version 3)."},
+	{Name: "priv_protocol", Type: config.TypeString, Description: "The privacy protocol (version 3)."},
+	{Name: "priv_password", Type: config.TypeString, Secret: true, Description: "The privacy password (version 3)."},
+	{Name: "exclude_ports", Type: config.TypeString, Description: "The (comma separated) ports whose hosts are ignored."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the agents."},
+	{Name: "timeout", Type: config.TypeDuration, Default: defaultTimeout.String(), Description: "The timeout of an SNMP request."},
+}
+
+const name = "snmp"
+
+const defaultPollInterval = 5 * time.Minute
+
+const defaultTimeout = 2 * time.Second
+
+const defaultCommunity = "public"
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES256":  gosnmp.AES256,
+	"AES192C": gosnmp.AES192C,
+	"AES256C": gosnmp.AES256C,
+}
+
+type snmpTracker struct {
+	targets      []string
+	version      gosnmp.SnmpVersion
+	community    string
+	msgFlags     gosnmp.SnmpV3MsgFlags
+	usm          *gosnmp.UsmSecurityParameters
+	timeout      time.Duration
+	excludePorts []string
+	pollInterval time.Duration
+
+	connect func(target string) (walker, error)
+}
+
+func newSNMPTracker(cfg config.Settings) (device.Tracker, error) {
+	if err := schema.Missing(cfg); err != nil {
+		return nil, err
+	}
+	t := &snmpTracker{
+		targets:   schema.List(cfg, "targets"),
+		community: schema.Value(cfg, "community"),
+	}
+	t.connect = t.dial
+	if v, found := cfg["exclude_ports"]; found {
+		t.excludePorts = config.SplitList(v)
+	}
+	var err error
+	if t.pollInterval, err = schema.Duration(cfg, "poll_interval"); err != nil {
+		return nil, err
+	}
+	if t.timeout, err = schema.Duration(cfg, "timeout"); err != nil {
+		return nil, err
+	}
+
+	switch schema.Value(cfg, "version") {
+	case "1":
+		t.version = gosnmp.Version1
+	case "2c":
+		t.version = gosnmp.Version2c
+	case "3":
+		t.version = gosnmp.Version3
+		if err := t.configureUSM(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid version setting value: %s", cfg["version"])
+	}
+	return t, nil
+}
+
+func (t *snmpTracker) configureUSM(cfg config.Settings) error {
+	username, found := cfg["username"]
+	if !found {
+		return errors.New("missing 'username' setting")
+	}
+	t.usm = &gosnmp.UsmSecurityParameters{UserName: username}
+	t.msgFlags = gosnmp.NoAuthNoPriv
+
+	if v, found := cfg["auth_protocol"]; found {
+		protocol, ok := authProtocols[strings.ToUpper(v)]
+		if !ok {
+			return fmt.Errorf("invalid auth_protocol setting value: %s", v)
+		}
+		t.usm.AuthenticationProtocol = protocol
+		t.usm.AuthenticationPassphrase = cfg["auth_password"]
+		t.msgFlags = gosnmp.AuthNoPriv
+
+		if v, found := cfg["priv_protocol"]; found {
+			protocol, ok := privProtocols[strings.ToUpper(v)]
+			if !ok {
+				return fmt.Errorf("invalid priv_protocol setting value: %s", v)
+			}
+			t.usm.PrivacyProtocol = protocol
+			t.usm.PrivacyPassphrase = cfg["priv_password"]
+			t.msgFlags = gosnmp.AuthPriv
+		}
+	}
+	return nil
+}
+
+func (t *snmpTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	ticker := time.NewTicker(1 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopped: %s tracker", name)
+			return nil
+
+		case <-ticker.C:
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
+		}
+	}
+}
+
+func (t *snmpTracker) poll(deviceReport device.ReportPresenceFunc) {
+	itfs := []model.DetectedInterface{}
+	for _, target := range t.targets {
+		w, err := t.connect(target)
+		if err != nil {
+			log.Errorf("[%s] %s: %s", name, target, err)
+			continue
+		}
+		detected, err := t.collect(target, w)
+		w.close()
+		if err != nil {
+			log.Errorf("[%s] %s: %s", name, target, err)
+			continue
+		}
+		log.Debugf("[%s] %s: detected %d address(es)", name, target, len(detected))
+		itfs = append(itfs, detected...)
+	}
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+}
+
+// collect merges the forwarding table (MAC address to port) with the
+// ARP table (MAC address to IP address) of a target.
+func (t *snmpTracker) collect(target string, w walker) ([]model.DetectedInterface, error) {
+	entries, err := forwardingEntries(w)
+	if err != nil {
+		return nil, err
+	}
+	arp, err := neighbors(w)
+	if err != nil {
+		// e.g. a layer 2 only switch
+		log.Debugf("[%s] %s: no ARP table: %s", name, target, err)
+	}
+	ips := make(map[string]string)
+	for _, n := range arp {
+		ips[n.MACAddress] = n.IPv4Address
+	}
+
+	seen := make(map[string]bool)
+	itfs := []model.DetectedInterface{}
+	for _, e := range entries {
+		if seen[e.MACAddress] || slices.Contains(t.excludePorts, e.Port) {
+			// e.g. addresses learned through an uplink or an access point port
+			continue
+		}
+		seen[e.MACAddress] = true
+		itf := model.DetectedInterface{
+			Interface: model.Interface{
+				Type:        model.InterfaceUnknown,
+				MACAddress:  e.MACAddress,
+				IPv4Address: ips[e.MACAddress],
+			},
+			Data: map[string]string{
+				"Switch":     target,
+				"SwitchPort": e.Port,
+			},
+		}
+		if e.VLAN > 0 {
+			itf.Data["VLAN"] = strconv.Itoa(e.VLAN)
+		}
+		itfs = append(itfs, itf)
+	}
+	for _, n := range arp {
+		if seen[n.MACAddress] {
+			continue
+		}
+		seen[n.MACAddress] = true
+		itfs = append(itfs, model.DetectedInterface{
+			Interface: model.Interface{
+				Type:        model.InterfaceUnknown,
+				MACAddress:  n.MACAddress,
+				IPv4Address: n.IPv4Address,
+			},
+		})
+	}
+	return itfs, nil
+}
+
+func (t *snmpTracker) dial(target string) (walker, error) {
+	host, port := target, uint16(161)
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		v, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		host, port = h, uint16(v)
+	}
+	client := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      port,
+		Version:   t.version,
+		Community: t.community,
+		Timeout:   t.timeout,
+		Retries:   1,
+	}
+	if t.version == gosnmp.Version3 {
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = t.msgFlags
+		client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			UserName:                 t.usm.UserName,
+			AuthenticationProtocol:   t.usm.AuthenticationProtocol,
+			AuthenticationPassphrase: t.usm.AuthenticationPassphrase,
+			PrivacyProtocol:          t.usm.PrivacyProtocol,
+			PrivacyPassphrase:        t.usm.PrivacyPassphrase,
+		}
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return &snmpWalker{client: client}, nil
+}
+
+func (t *snmpTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
+
+type snmpWalker struct {
+	client *gosnmp.GoSNMP
+}
+
+func (w *snmpWalker) walk(oid string) ([]gosnmp.SnmpPDU, error) {
+	if w.client.Version == gosnmp.Version1 {
+		return w.client.WalkAll(oid)
+	}
+	return w.client.BulkWalkAll(oid)
+}
+
+func (w *snmpWalker) close() {
+	w.client.Conn.Close()
+}
@@ -0,0 +1,214 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// recordedAgent replays SNMP walk responses captured from a managed switch.
+type recordedAgent struct {
+	tables map[string][]gosnmp.SnmpPDU
+	closed bool
+}
+
+func (a *recordedAgent) walk(oid string) ([]gosnmp.SnmpPDU, error) {
+	return a.tables[oid], nil
+}
+
+func (a *recordedAgent) close() {
+	a.closed = true
+}
+
+func octets(name string, value string) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.OctetString, Value: []byte(value)}
+}
+
+func integer(name string, value int) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.Integer, Value: value}
+}
+
+var switchTables = map[string][]gosnmp.SnmpPDU{
+	oidIfName: {
+		octets(".1.3.6.1.2.1.31.1.1.1.1.1", "gi1"),
+		octets(".1.3.6.1.2.1.31.1.1.1.1.2", "gi2"),
+		octets(".1.3.6.1.2.1.31.1.1.1.1.8", "gi8"),
+	},
+	oidDot1dBasePortIfIndex: {
+		integer(".1.3.6.1.2.1.17.1.4.1.2.1", 1),
+		integer(".1.3.6.1.2.1.17.1.4.1.2.2", 2),
+		integer(".1.3.6.1.2.1.17.1.4.1.2.8", 8),
+	},
+	oidDot1qTpFdbPort: {
+		integer(".1.3.6.1.2.1.17.7.1.2.2.1.2.1.0.17.50.1.2.3", 1),
+		integer(".1.3.6.1.2.1.17.7.1.2.2.1.2.10.0.17.50.1.2.4", 2),
+		integer(".1.3.6.1.2.1.17.7.1.2.2.1.2.1.0.17.50.1.2.5", 8),
+		integer(".1.3.6.1.2.1.17.7.1.2.2.1.2.1.0.17.50.1.2.6", 0),
+	},
+	oidIPNetToPhysicalPhysAddress: {
+		octets(".1.3.6.1.2.1.4.35.1.2.3.1.4.192.10.20.30", "\x00\x11\x32\x01\x02\x03"),
+		octets(".1.3.6.1.2.1.4.35.1.2.3.1.4.192.10.20.31", "\x00\x11\x32\x01\x02\x05"),
+		octets(".1.3.6.1.2.1.4.35.1.2.3.1.4.192.10.20.32", "\x00\x11\x32\x01\x02\x07"),
+		octets(".1.3.6.1.2.1.4.35.1.2.3.2.16.254.128.0.0.0.0.0.0.2.17.50.255.254.1.2.3", "\x00\x11\x32\x01\x02\x03"),
+	},
+}
+
+var legacySwitchTables = map[string][]gosnmp.SnmpPDU{
+	oidIfDescr: {
+		octets(".1.3.6.1.2.1.2.2.1.2.101", "Port 1"),
+	},
+	oidDot1dBasePortIfIndex: {
+		integer(".1.3.6.1.2.1.17.1.4.1.2.1", 101),
+	},
+	oidDot1dTpFdbPort: {
+		integer(".1.3.6.1.2.1.17.4.3.1.2.0.17.50.1.2.3", 1),
+		integer(".1.3.6.1.2.1.17.4.3.1.2.0.17.50.1.2.4", 3),
+	},
+	oidIPNetToMediaPhysAddress: {
+		octets(".1.3.6.1.2.1.4.22.1.2.101.192.10.20.30", "\x00\x11\x32\x01\x02\x03"),
+	},
+}
+
+func TestNew(t *testing.T) {
+	tr, err := newSNMPTracker(config.Settings{"targets": "192.10.20.5, 192.10.20.6:1161"})
+	assert.NoError(t, err)
+	tracker := tr.(*snmpTracker)
+	assert.Equal(t, []string{"192.10.20.5", "192.10.20.6:1161"}, tracker.targets)
+	assert.Equal(t, gosnmp.Version2c, tracker.version)
+	assert.Equal(t, defaultCommunity, tracker.community)
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+	assert.Equal(t, defaultTimeout, tracker.timeout)
+	assert.Empty(t, tracker.excludePorts)
+
+	tr, err = newSNMPTracker(config.Settings{
+		"targets":       "192.10.20.5",
+		"version":       "3",
+		"username":      "monitor",
+		"auth_protocol": "sha256",
+		"auth_password": "foo",
+		"priv_protocol": "aes",
+		"priv_password": "bar",
+		"exclude_ports": "gi8,gi9",
+		"poll_interval": "1m",
+	})
+	assert.NoError(t, err)
+	tracker = tr.(*snmpTracker)
+	assert.Equal(t, gosnmp.Version3, tracker.version)
+	assert.Equal(t, gosnmp.AuthPriv, tracker.msgFlags)
+	assert.Equal(t, &gosnmp.UsmSecurityParameters{
+		UserName:                 "monitor",
+		AuthenticationProtocol:   gosnmp.SHA256,
+		AuthenticationPassphrase: "foo",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "bar",
+	}, tracker.usm)
+	assert.Equal(t, []string{"gi8", "gi9"}, tracker.excludePorts)
+	assert.Equal(t, 1*time.Minute, tracker.pollInterval)
+
+	_, err = newSNMPTracker(config.Settings{"targets": "192.10.20.5", "version": "4"})
+	assert.EqualError(t, err, "invalid version setting value: 4")
+	_, err = newSNMPTracker(config.Settings{"targets": "192.10.20.5", "version": "3"})
+	assert.EqualError(t, err, "missing 'username' setting")
+}
+
+func TestLoop(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := snmpTracker{}
+
+	go tracker.Loop(nil, ctx, wg)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestPoll(t *testing.T) {
+	agent := &recordedAgent{tables: switchTables}
+	tr, err := newSNMPTracker(config.Settings{"targets": "switch", "exclude_ports": "gi8"})
+	assert.NoError(t, err)
+	tracker := tr.(*snmpTracker)
+	tracker.connect = func(target string) (walker, error) {
+		assert.Equal(t, "switch", target)
+		return agent, nil
+	}
+
+	reported := []model.DetectedInterface{}
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		reported = append(reported, itfs...)
+	})
+
+	assert.True(t, agent.closed)
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:03", IPv4Address: "192.10.20.30"},
+			Data:      map[string]string{"Switch": "switch", "SwitchPort": "gi1", "VLAN": "1"},
+		},
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:04"},
+			Data:      map[string]string{"Switch": "switch", "SwitchPort": "gi2", "VLAN": "10"},
+		},
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:05", IPv4Address: "192.10.20.31"},
+		},
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:07", IPv4Address: "192.10.20.32"},
+		},
+	}, reported)
+}
+
+func TestPollWithLegacyTables(t *testing.T) {
+	tr, err := newSNMPTracker(config.Settings{"targets": "switch"})
+	assert.NoError(t, err)
+	tracker := tr.(*snmpTracker)
+	tracker.connect = func(target string) (walker, error) {
+		return &recordedAgent{tables: legacySwitchTables}, nil
+	}
+
+	reported := []model.DetectedInterface{}
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		reported = append(reported, itfs...)
+	})
+
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:03", IPv4Address: "192.10.20.30"},
+			Data:      map[string]string{"Switch": "switch", "SwitchPort": "Port 1"},
+		},
+		{
+			Interface: model.Interface{MACAddress: "00:11:32:01:02:04"},
+			Data:      map[string]string{"Switch": "switch", "SwitchPort": "3"},
+		},
+	}, reported)
+}
+
+func TestPollWithUnreachableTarget(t *testing.T) {
+	tr, err := newSNMPTracker(config.Settings{"targets": "switch"})
+	assert.NoError(t, err)
+	tracker := tr.(*snmpTracker)
+	tracker.connect = func(target string) (walker, error) {
+		return nil, errors.New("request timeout")
+	}
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+}
+
+func TestSuffix(t *testing.T) {
+	index, ok := suffix(".1.3.6.1.2.1.17.4.3.1.2.0.17.50.1.2.3", oidDot1dTpFdbPort)
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 17, 50, 1, 2, 3}, index)
+
+	_, ok = suffix(".1.3.6.1.2.1.17.4.3.1.20.1", oidDot1dTpFdbPort)
+	assert.False(t, ok)
+}
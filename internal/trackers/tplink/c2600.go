@@ -16,15 +16,30 @@ import (
 
 const c2600 = "tplink-c2600"
 
-func newArcherC2600Tracker(cfg config.Settings) device.Tracker {
-	return &tplinkTracker{
-		name:     c2600,
-		baseURL:  ensureSetting("url", cfg, c2600),
-		username: ensureSetting("username", cfg, c2600),
-		password: ensureSetting("password", cfg, c2600),
-		login:    c2600Login,
-		status:   c2600Status,
+var c2600Schema = config.Schema{
+	{Name: "url", Type: config.TypeString, Required: true, Description: "The URL of the router."},
+	{Name: "username", Type: config.TypeString, Required: true, Description: "The user name."},
+	{Name: "password", Type: config.TypeString, Required: true, Secret: true, Description: "The password."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the clients."},
+}
+
+func newArcherC2600Tracker(cfg config.Settings) (device.Tracker, error) {
+	if err := c2600Schema.Missing(cfg); err != nil {
+		return nil, err
 	}
+	interval, err := c2600Schema.Duration(cfg, "poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	return &tplinkTracker{
+		name:         c2600,
+		baseURL:      cfg["url"],
+		username:     cfg["username"],
+		password:     cfg["password"],
+		pollInterval: interval,
+		login:        c2600Login,
+		status:       c2600Status,
+	}, nil
 }
 
 const c2600SessionCookie = "sysauth"
@@ -90,6 +105,7 @@ func c2600Status(baseUrl string, a credentials) (statusResponse, error) {
 	if err != nil {
 		return response, err
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
 		return response, errors.New("unexpected http response " + res.Status)
 	}
@@ -0,0 +1,151 @@
+package tplink
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// c2600Router is a stand-in for the Archer C2600 router web API.
+type c2600Router struct {
+	logins   int
+	statuses int
+	expired  bool
+}
+
+func (r *c2600Router) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/luci/;stok=/login", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "login", req.URL.Query().Get("form"))
+		req.ParseForm()
+		if req.PostForm.Get("username") != "admin" || req.PostForm.Get("password") != "secret" {
+			rw.Write([]byte(`{"success": false, "errorcode": "login failed"}`))
+			return
+		}
+		r.logins++
+		r.expired = false
+		http.SetCookie(rw, &http.Cookie{Name: c2600SessionCookie, Value: "nonce"})
+		rw.Write([]byte(`{"success": true, "data": {"stok": "token"}}`))
+	})
+	mux.HandleFunc("/cgi-bin/luci/;stok=token/admin/status", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "all", req.URL.Query().Get("form"))
+		c, err := req.Cookie(c2600SessionCookie)
+		if err != nil || c.Value != "nonce" || r.expired {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		r.statuses++
+		rw.Write([]byte(`{
+			"success": true,
+			"data": {
+				"access_devices_wired": [
+					{"macaddr": "AA-BB-CC-DD-EE-01", "ipaddr": "192.168.0.10", "hostname": "nas"}
+				],
+				"access_devices_wireless_host": [
+					{"macaddr": "AA-BB-CC-DD-EE-02", "ipaddr": "192.168.0.11", "hostname": "phone"},
+					{"macaddr": "AA-BB-CC-DD-EE-03", "ipaddr": "192.168.0.12", "hostname": "--"}
+				]
+			}
+		}`))
+	})
+	return mux
+}
+
+func TestNewArcherC2600Tracker(t *testing.T) {
+	cfg := config.Settings{
+		"url":      "http://192.168.0.1",
+		"username": "admin",
+		"password": "secret",
+	}
+	tr, err := newArcherC2600Tracker(cfg)
+	assert.NoError(t, err)
+	tracker := tr.(*tplinkTracker)
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+
+	cfg["poll_interval"] = "1m"
+	tr, err = newArcherC2600Tracker(cfg)
+	assert.NoError(t, err)
+	tracker = tr.(*tplinkTracker)
+	assert.Equal(t, "1m0s", tracker.pollInterval.String())
+
+	cfg["poll_interval"] = "soon"
+	_, err = newArcherC2600Tracker(cfg)
+	assert.EqualError(t, err, "invalid poll_interval setting value: soon")
+	_, err = newArcherC2600Tracker(config.Settings{"url": "http://192.168.0.1"})
+	assert.EqualError(t, err, "missing 'username' setting")
+}
+
+func TestC2600Poll(t *testing.T) {
+	router := &c2600Router{}
+	server := httptest.NewServer(router.handler(t))
+	defer server.Close()
+
+	tr, err := newArcherC2600Tracker(config.Settings{
+		"url":      server.URL,
+		"username": "admin",
+		"password": "secret",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*tplinkTracker)
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{Type: model.InterfaceEthernet, MACAddress: "AA:BB:CC:DD:EE:01", IPv4Address: "192.168.0.10"},
+			Data:      map[string]string{"Identifier": "nas", "Description": "nas"},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:02", IPv4Address: "192.168.0.11"},
+			Data:      map[string]string{"Identifier": "phone", "Description": "phone"},
+		},
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:03", IPv4Address: "192.168.0.12"},
+		},
+	}, reports[0])
+
+	// the session is reused
+	tracker.poll(report)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, 1, router.logins)
+	assert.Equal(t, 2, router.statuses)
+
+	// the session expired => login again
+	router.expired = true
+	tracker.poll(report)
+	assert.Equal(t, 3, len(reports))
+	assert.Equal(t, 2, router.logins)
+	assert.Equal(t, 3, router.statuses)
+}
+
+func TestC2600PollWithInvalidCredentials(t *testing.T) {
+	router := &c2600Router{}
+	server := httptest.NewServer(router.handler(t))
+	defer server.Close()
+
+	tr, err := newArcherC2600Tracker(config.Settings{
+		"url":      server.URL,
+		"username": "admin",
+		"password": "wrong",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*tplinkTracker)
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+	assert.Nil(t, tracker.session)
+}
@@ -18,14 +18,28 @@ import (
 
 const re450 = "tplink-re450"
 
-func newRE450Tracker(cfg config.Settings) device.Tracker {
-	return &tplinkTracker{
-		name:     re450,
-		baseURL:  ensureSetting("url", cfg, re450),
-		password: ensureSetting("password", cfg, re450),
-		login:    re450Login,
-		status:   re450Status,
+var re450Schema = config.Schema{
+	{Name: "url", Type: config.TypeString, Required: true, Description: "The URL of the range extender."},
+	{Name: "password", Type: config.TypeString, Required: true, Secret: true, Description: "The password."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the clients."},
+}
+
+func newRE450Tracker(cfg config.Settings) (device.Tracker, error) {
+	if err := re450Schema.Missing(cfg); err != nil {
+		return nil, err
 	}
+	interval, err := re450Schema.Duration(cfg, "poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	return &tplinkTracker{
+		name:         re450,
+		baseURL:      cfg["url"],
+		password:     cfg["password"],
+		pollInterval: interval,
+		login:        re450Login,
+		status:       re450Status,
+	}, nil
 }
 
 const re450SessionCookie = "COOKIE"
@@ -145,6 +159,9 @@ func re450Status(baseUrl string, a credentials) (statusResponse, error) {
 	if err != nil {
 		return status, err
 	}
+	if r.Timeout {
+		return status, errors.New("session timeout")
+	}
 	if !r.Success {
 		return status, fmt.Errorf("error code: %d", r.ErrorCode)
 	}
@@ -1,9 +1,13 @@
 package tplink
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
 )
 
 func TestMD5(t *testing.T) {
@@ -15,3 +19,80 @@ func TestRE450Token(t *testing.T) {
 	r := re450Token("foobar", "nonce")
 	assert.Equal(t, "0AA33C47954A9397B25860B8C0DAF623", r)
 }
+
+// re450Extender is a stand-in for the RE450 range extender web API.
+type re450Extender struct {
+	logins  int
+	expired bool
+}
+
+func (e *re450Extender) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "GET", req.Method)
+		http.SetCookie(rw, &http.Cookie{Name: re450SessionCookie, Value: "nonce"})
+	})
+	mux.HandleFunc("/data/login.json", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		req.ParseForm()
+		if req.PostForm.Get("encoded") != re450Token("secret", "nonce") {
+			rw.Write([]byte(`{"success": false, "errorcode": "invalid password"}`))
+			return
+		}
+		e.logins++
+		e.expired = false
+		http.SetCookie(rw, &http.Cookie{Name: re450SessionCookie, Value: "session"})
+		rw.Write([]byte(`{"success": true}`))
+	})
+	mux.HandleFunc("/data/device.all.json", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		c, err := req.Cookie(re450SessionCookie)
+		if err != nil || c.Value != "session" || e.expired {
+			rw.Write([]byte(`{"success": false, "timeout": true}`))
+			return
+		}
+		rw.Write([]byte(`{
+			"success": true,
+			"data": [
+				{"mac": "AA-BB-CC-DD-EE-04", "ipaddr": "192.168.0.20", "name": "tablet"}
+			]
+		}`))
+	})
+	return mux
+}
+
+func TestRE450Poll(t *testing.T) {
+	extender := &re450Extender{}
+	server := httptest.NewServer(extender.handler(t))
+	defer server.Close()
+
+	tr, err := newRE450Tracker(config.Settings{
+		"url":      server.URL,
+		"password": "secret",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*tplinkTracker)
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, []model.DetectedInterface{
+		{
+			Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "AA:BB:CC:DD:EE:04", IPv4Address: "192.168.0.20"},
+			Data:      map[string]string{"Identifier": "tablet", "Description": "tablet"},
+		},
+	}, reports[0])
+
+	tracker.poll(report)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, 1, extender.logins)
+
+	extender.expired = true
+	tracker.poll(report)
+	assert.Equal(t, 3, len(reports))
+	assert.Equal(t, 2, extender.logins)
+}
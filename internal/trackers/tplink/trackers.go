@@ -2,19 +2,20 @@ package tplink
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/touchardv/myhome-presence/internal/config"
 	"github.com/touchardv/myhome-presence/internal/device"
 	"github.com/touchardv/myhome-presence/pkg/model"
 )
 
 // EnableTrackers registers the "tplink" trackers.
 func EnableTrackers() {
-	device.Register("tplink-c2600", newArcherC2600Tracker)
-	device.Register("tplink-re450", newRE450Tracker)
+	device.Register(c2600, newArcherC2600Tracker, c2600Schema)
+	device.Register(re450, newRE450Tracker, re450Schema)
 }
 
 type loginFunc func(baseUrl string, username string, password string) (credentials, error)
@@ -22,12 +23,14 @@ type loginFunc func(baseUrl string, username string, password string) (credentia
 type statusFunc func(baseUrl string, c credentials) (statusResponse, error)
 
 type tplinkTracker struct {
-	name     string
-	baseURL  string
-	username string
-	password string
-	login    loginFunc
-	status   statusFunc
+	name         string
+	baseURL      string
+	username     string
+	password     string
+	pollInterval time.Duration
+	login        loginFunc
+	status       statusFunc
+	session      *credentials
 }
 
 type credentials struct {
@@ -67,39 +70,74 @@ func (t *tplinkTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context
 			return nil
 
 		case <-ticker.C:
-			ticker.Reset(5 * time.Minute)
-			c, err := t.login(t.baseURL, t.username, t.password)
-			if err != nil {
-				log.Errorf("[%s] login failed: %s", t.name, err)
-				break
-			}
-			r, err := t.status(t.baseURL, c)
-			if err != nil {
-				log.Errorf("[%s] status failed: %s", t.name, err)
-				break
-			}
-			log.Debugf("[%s] detected %d wired device(s)", t.name, len(r.Data.WiredDevices))
-			for _, device := range r.Data.WiredDevices {
-				itf := model.Interface{Type: model.InterfaceEthernet, IPv4Address: device.IPAddress}
-				deviceReport([]model.DetectedInterface{{Interface: itf}})
-			}
-			log.Debugf("[%s] detected %d wireless device(s)", t.name, len(r.Data.WirelessDevices))
-			for _, device := range r.Data.WirelessDevices {
-				itf := model.Interface{Type: model.InterfaceWifi, IPv4Address: device.IPAddress}
-				deviceReport([]model.DetectedInterface{{Interface: itf}})
-			}
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
 		}
 	}
 }
 
-func (t *tplinkTracker) Ping([]model.Device) {
-	// Nothing to be done here. The tracker is purely asynchronous.
+func (t *tplinkTracker) poll(deviceReport device.ReportPresenceFunc) {
+	r, err := t.fetchStatus()
+	if err != nil {
+		log.Errorf("[%s] %s", t.name, err)
+		return
+	}
+	itfs := []model.DetectedInterface{}
+	log.Debugf("[%s] detected %d wired device(s)", t.name, len(r.Data.WiredDevices))
+	for _, device := range r.Data.WiredDevices {
+		itfs = append(itfs, toDetectedInterface(model.InterfaceEthernet, device))
+	}
+	log.Debugf("[%s] detected %d wireless device(s)", t.name, len(r.Data.WirelessDevices))
+	for _, device := range r.Data.WirelessDevices {
+		itfs = append(itfs, toDetectedInterface(model.InterfaceWifi, device))
+	}
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
 }
 
-func ensureSetting(key string, cfg config.Settings, name string) string {
-	if v, found := cfg[key]; found {
-		return v
+// fetchStatus retrieves the router status, reusing the current session
+// if any, or logging in again when there is none or when it has expired.
+func (t *tplinkTracker) fetchStatus() (statusResponse, error) {
+	if t.session != nil {
+		r, err := t.status(t.baseURL, *t.session)
+		if err == nil {
+			return r, nil
+		}
+		log.Debugf("[%s] status failed (session may have expired): %s", t.name, err)
+		t.session = nil
+	}
+
+	c, err := t.login(t.baseURL, t.username, t.password)
+	if err != nil {
+		return statusResponse{}, fmt.Errorf("login failed: %w", err)
+	}
+	r, err := t.status(t.baseURL, c)
+	if err != nil {
+		return r, fmt.Errorf("status failed: %w", err)
 	}
-	log.Fatalf("[%s] Missing device '%s' configuration setting", name, key)
-	return ""
+	t.session = &c
+	return r, nil
 }
+
+func toDetectedInterface(t model.InterfaceType, d statusDataDevice) model.DetectedInterface {
+	itf := model.DetectedInterface{Interface: model.Interface{
+		Type:        t,
+		IPv4Address: d.IPAddress,
+		MACAddress:  strings.ReplaceAll(d.MACAddress, "-", ":"),
+	}}
+	hostname := strings.TrimSpace(d.Hostname)
+	if len(hostname) > 0 && hostname != "--" {
+		itf.Data = map[string]string{
+			device.ReportDataSuggestedIdentifier:  hostname,
+			device.ReportDataSuggestedDescription: hostname,
+		}
+	}
+	return itf
+}
+
+func (t *tplinkTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
+
+const defaultPollInterval = 5 * time.Minute
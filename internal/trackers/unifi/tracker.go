@@ -0,0 +1,263 @@
+package unifi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/internal/device"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// EnableTracker registers the "unifi" tracker so that it can be used.
+func EnableTracker() {
+	device.Register(name, newUnifiTracker, schema)
+}
+
+var schema = config.Schema{
+	{Name: "url", Type: config.TypeString, Required: true, Description: "The URL of the UniFi controller."},
+	{Name: "username", Type: config.TypeString, Required: true, Description: "The user name."},
+	{Name: "password", Type: config.TypeString, Required: true, Secret: true, Description: "The password."},
+	{Name: "site", Type: config.TypeString, Default: defaultSite, Description: "The site of the clients."},
+	{Name: "unifi_os", Type: config.TypeBool, Default: "false", Description: "Whether the controller runs on UniFi OS."},
+	{Name: "insecure_skip_verify", Type: config.TypeBool, Default: "false", Description: "Whether the TLS certificate is not verified."},
+	{Name: "poll_interval", Type: config.TypeDuration, Default: defaultPollInterval.String(), Description: "The interval between two polls of the clients."},
+}
+
+const name = "unifi"
+
+const defaultPollInterval = 1 * time.Minute
+
+const defaultSite = "default"
+
+var errUnauthorized = errors.New("unauthorized")
+
+type unifiTracker struct {
+	baseURL      string
+	client       *http.Client
+	csrfToken    string
+	loggedIn     bool
+	username     string
+	password     string
+	pollInterval time.Duration
+	site         string
+	unifiOS      bool
+}
+
+type client struct {
+	MACAddress  string `json:"mac"`
+	IPv4Address string `json:"ip"`
+	Hostname    string `json:"hostname"`
+	Name        string `json:"name"`
+	APMAC       string `json:"ap_mac"`
+	ESSID       string `json:"essid"`
+	Signal      int    `json:"signal"`
+	LastSeen    int64  `json:"last_seen"`
+	IsWired     bool   `json:"is_wired"`
+}
+
+type statResponse struct {
+	Meta struct {
+		RC  string `json:"rc"`
+		Msg string `json:"msg"`
+	} `json:"meta"`
+	Data []client `json:"data"`
+}
+
+func newUnifiTracker(cfg config.Settings) (device.Tracker, error) {
+	if err := schema.Missing(cfg); err != nil {
+		return nil, err
+	}
+	interval, err := schema.Duration(cfg, "poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	unifiOS, err := schema.Bool(cfg, "unifi_os")
+	if err != nil {
+		return nil, err
+	}
+	insecure, err := schema.Bool(cfg, "insecure_skip_verify")
+	if err != nil {
+		return nil, err
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return &unifiTracker{
+		baseURL: strings.TrimSuffix(cfg["url"], "/"),
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			},
+		},
+		username:     cfg["username"],
+		password:     cfg["password"],
+		pollInterval: interval,
+		site:         schema.Value(cfg, "site"),
+		unifiOS:      unifiOS,
+	}, nil
+}
+
+func (t *unifiTracker) Loop(deviceReport device.ReportPresenceFunc, ctx context.Context, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	log.Infof("Starting: %s tracker", name)
+	ticker := time.NewTicker(1 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopped: %s tracker", name)
+			return nil
+
+		case <-ticker.C:
+			ticker.Reset(t.pollInterval)
+			t.poll(deviceReport)
+		}
+	}
+}
+
+func (t *unifiTracker) poll(deviceReport device.ReportPresenceFunc) {
+	clients, err := t.fetchClients()
+	if err != nil {
+		log.Errorf("[%s] %s", name, err)
+		return
+	}
+	log.Debugf("[%s] detected %d client(s)", name, len(clients))
+	itfs := []model.DetectedInterface{}
+	for _, c := range clients {
+		itfs = append(itfs, toDetectedInterface(c))
+	}
+	if len(itfs) > 0 {
+		deviceReport(itfs)
+	}
+}
+
+// fetchClients retrieves the connected clients, logging in first when
+// needed or when the controller rejected the current session.
+func (t *unifiTracker) fetchClients() ([]client, error) {
+	if t.loggedIn {
+		clients, err := t.stations()
+		if !errors.Is(err, errUnauthorized) {
+			return clients, err
+		}
+		log.Debugf("[%s] session was rejected", name)
+		t.loggedIn = false
+	}
+
+	if err := t.login(); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	t.loggedIn = true
+	return t.stations()
+}
+
+func (t *unifiTracker) login() error {
+	path := "/api/login"
+	if t.unifiOS {
+		path = "/api/auth/login"
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"username": t.username,
+		"password": t.password,
+		"remember": false,
+	})
+	req, _ := http.NewRequest("POST", t.baseURL+path, bytes.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.New("unexpected http response " + res.Status)
+	}
+	t.csrfToken = res.Header.Get("X-Csrf-Token")
+	return nil
+}
+
+func (t *unifiTracker) stations() ([]client, error) {
+	prefix := ""
+	if t.unifiOS {
+		prefix = "/proxy/network"
+	}
+	url := fmt.Sprintf("%s%s/api/s/%s/stat/sta", t.baseURL, prefix, t.site)
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Add("Accept", "application/json")
+	if len(t.csrfToken) > 0 {
+		req.Header.Add("X-Csrf-Token", t.csrfToken)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized {
+		return nil, errUnauthorized
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected http response " + res.Status)
+	}
+	if v := res.Header.Get("X-Updated-Csrf-Token"); len(v) > 0 {
+		t.csrfToken = v
+	}
+	response := statResponse{}
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Meta.RC != "ok" {
+		return nil, errors.New("unexpected response: " + response.Meta.Msg)
+	}
+	return response.Data, nil
+}
+
+func toDetectedInterface(c client) model.DetectedInterface {
+	itf := model.DetectedInterface{
+		Interface: model.Interface{
+			Type:        model.InterfaceWifi,
+			MACAddress:  c.MACAddress,
+			IPv4Address: c.IPv4Address,
+		},
+		Data: map[string]string{},
+	}
+	if c.IsWired {
+		itf.Type = model.InterfaceEthernet
+	} else {
+		device.SetData(itf.Data, "AccessPoint", c.APMAC)
+		device.SetData(itf.Data, "ESSID", c.ESSID)
+		if c.Signal != 0 {
+			itf.Data["Signal"] = strconv.Itoa(c.Signal)
+		}
+	}
+	n := c.Name
+	if len(strings.TrimSpace(n)) == 0 {
+		n = c.Hostname
+	}
+	device.SetData(itf.Data, device.ReportDataSuggestedIdentifier, n)
+	device.SetData(itf.Data, device.ReportDataSuggestedDescription, n)
+	if c.LastSeen > 0 {
+		// rely on the controller own knowledge rather than the poll time
+		itf.LastSeenAt = time.Unix(c.LastSeen, 0)
+	}
+	return itf
+}
+
+func (t *unifiTracker) Ping([]model.Device) {
+	// Nothing to be done here. The tracker is purely asynchronous.
+}
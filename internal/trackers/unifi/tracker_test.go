@@ -0,0 +1,217 @@
+package unifi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/touchardv/myhome-presence/internal/config"
+	"github.com/touchardv/myhome-presence/pkg/model"
+)
+
+// fakeController is a stand-in for a UniFi Network controller (either a
+// classic controller or a UniFi OS console).
+type fakeController struct {
+	t       *testing.T
+	unifiOS bool
+	logins  int
+	expired bool
+}
+
+func (c *fakeController) handler() http.Handler {
+	prefix := ""
+	loginPath := "/api/login"
+	if c.unifiOS {
+		prefix = "/proxy/network"
+		loginPath = "/api/auth/login"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(loginPath, func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(c.t, "POST", req.Method)
+		credentials := map[string]interface{}{}
+		json.NewDecoder(req.Body).Decode(&credentials)
+		if credentials["username"] != "admin" || credentials["password"] != "secret" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.logins++
+		c.expired = false
+		http.SetCookie(rw, &http.Cookie{Name: "unifises", Value: "session", Path: "/"})
+		if c.unifiOS {
+			rw.Header().Add("X-Csrf-Token", "csrf")
+		}
+		rw.Write([]byte(`{"meta": {"rc": "ok"}, "data": []}`))
+	})
+	mux.HandleFunc(prefix+"/api/s/home/stat/sta", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(c.t, "GET", req.Method)
+		cookie, err := req.Cookie("unifises")
+		if err != nil || cookie.Value != "session" || c.expired {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if c.unifiOS {
+			assert.Equal(c.t, "csrf", req.Header.Get("X-Csrf-Token"))
+		}
+		rw.Write([]byte(`{
+			"meta": {"rc": "ok"},
+			"data": [
+				{"mac": "aa:bb:cc:dd:ee:01", "ip": "192.168.1.20", "hostname": "phone", "ap_mac": "f0:9f:c2:00:00:01", "essid": "home", "signal": -55, "last_seen": 1700000000, "is_wired": false},
+				{"mac": "aa:bb:cc:dd:ee:02", "ip": "192.168.1.21", "hostname": "nas", "name": "My NAS", "last_seen": 1700000010, "is_wired": true}
+			]
+		}`))
+	})
+	return mux
+}
+
+var expectedInterfaces = []model.DetectedInterface{
+	{
+		Interface: model.Interface{Type: model.InterfaceWifi, MACAddress: "aa:bb:cc:dd:ee:01", IPv4Address: "192.168.1.20"},
+		Data: map[string]string{
+			"AccessPoint": "f0:9f:c2:00:00:01",
+			"Description": "phone",
+			"ESSID":       "home",
+			"Identifier":  "phone",
+			"Signal":      "-55",
+		},
+		LastSeenAt: time.Unix(1700000000, 0),
+	},
+	{
+		Interface: model.Interface{Type: model.InterfaceEthernet, MACAddress: "aa:bb:cc:dd:ee:02", IPv4Address: "192.168.1.21"},
+		Data: map[string]string{
+			"Description": "My NAS",
+			"Identifier":  "My NAS",
+		},
+		LastSeenAt: time.Unix(1700000010, 0),
+	},
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.Settings{
+		"url":      "https://192.168.1.2:8443/",
+		"username": "admin",
+		"password": "secret",
+	}
+	tr, err := newUnifiTracker(cfg)
+	assert.NoError(t, err)
+	tracker := tr.(*unifiTracker)
+	assert.Equal(t, "https://192.168.1.2:8443", tracker.baseURL)
+	assert.Equal(t, defaultSite, tracker.site)
+	assert.Equal(t, defaultPollInterval, tracker.pollInterval)
+	assert.False(t, tracker.unifiOS)
+	assert.False(t, tracker.client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+
+	cfg["site"] = "home"
+	cfg["poll_interval"] = "2m"
+	cfg["unifi_os"] = "true"
+	cfg["insecure_skip_verify"] = "true"
+	tr, err = newUnifiTracker(cfg)
+	assert.NoError(t, err)
+	tracker = tr.(*unifiTracker)
+	assert.Equal(t, "home", tracker.site)
+	assert.Equal(t, 2*time.Minute, tracker.pollInterval)
+	assert.True(t, tracker.unifiOS)
+	assert.True(t, tracker.client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+
+	cfg["unifi_os"] = "maybe"
+	_, err = newUnifiTracker(cfg)
+	assert.EqualError(t, err, "invalid unifi_os setting value: maybe")
+}
+
+func TestLoop(t *testing.T) {
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := unifiTracker{}
+
+	go tracker.Loop(nil, ctx, wg)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestPollClassicController(t *testing.T) {
+	controller := &fakeController{t: t}
+	server := httptest.NewTLSServer(controller.handler())
+	defer server.Close()
+
+	tr, err := newUnifiTracker(config.Settings{
+		"url":                  server.URL,
+		"username":             "admin",
+		"password":             "secret",
+		"site":                 "home",
+		"insecure_skip_verify": "true",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*unifiTracker)
+
+	reports := [][]model.DetectedInterface{}
+	report := func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	}
+
+	tracker.poll(report)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, expectedInterfaces, reports[0])
+
+	// the session is reused
+	tracker.poll(report)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, 1, controller.logins)
+
+	// the session expired => login again
+	controller.expired = true
+	tracker.poll(report)
+	assert.Equal(t, 3, len(reports))
+	assert.Equal(t, 2, controller.logins)
+}
+
+func TestPollUnifiOSConsole(t *testing.T) {
+	controller := &fakeController{t: t, unifiOS: true}
+	server := httptest.NewTLSServer(controller.handler())
+	defer server.Close()
+
+	tr, err := newUnifiTracker(config.Settings{
+		"url":                  server.URL,
+		"username":             "admin",
+		"password":             "secret",
+		"site":                 "home",
+		"unifi_os":             "true",
+		"insecure_skip_verify": "true",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*unifiTracker)
+
+	reports := [][]model.DetectedInterface{}
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		reports = append(reports, itfs)
+	})
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, expectedInterfaces, reports[0])
+}
+
+func TestPollWithSelfSignedCertificate(t *testing.T) {
+	controller := &fakeController{t: t}
+	server := httptest.NewTLSServer(controller.handler())
+	defer server.Close()
+
+	tr, err := newUnifiTracker(config.Settings{
+		"url":      server.URL,
+		"username": "admin",
+		"password": "secret",
+		"site":     "home",
+	})
+	assert.NoError(t, err)
+	tracker := tr.(*unifiTracker)
+
+	called := false
+	tracker.poll(func(itfs []model.DetectedInterface) {
+		called = true
+	})
+	assert.False(t, called)
+	assert.Equal(t, 0, controller.logins)
+}
@@ -3,7 +3,8 @@ package model
 import "errors"
 
 var (
-	ErrInvalidDeviceAction = errors.New("invalid device action")
-	ErrMissingDeviceStatus = errors.New("missing device status")
-	ErrInvalidDeviceStatus = errors.New("invalid device status")
+	ErrInvalidDeviceAction  = errors.New("invalid device action")
+	ErrMissingDeviceStatus  = errors.New("missing device status")
+	ErrInvalidDeviceStatus  = errors.New("invalid device status")
+	ErrInvalidInterfaceType = errors.New("invalid interface type")
 )
@@ -3,6 +3,7 @@ package model
 import (
 	"bytes"
 	"encoding/json"
+	"time"
 )
 
 // InterfaceType defines the type of physical/software interface
@@ -13,12 +14,34 @@ type Interface struct {
 	Type        InterfaceType
 	MACAddress  string
 	IPv4Address string
+
+	// IRK is the Identity Resolving Key of a Bluetooth LE interface (as 32 hexadecimal
+	// digits), used for recognizing the interface behind its rotating private addresses.
+	IRK string `yaml:"irk,omitempty" json:"IRK,omitempty"`
+
+	// BeaconID is the stable identity advertised by a Bluetooth beacon (e.g.
+	// "ibeacon:<uuid>:<major>:<minor>" or "eddystone:<namespace>:<instance>"),
+	// matched instead of the (possibly rotating) MAC address.
+	BeaconID string `yaml:"beacon_id,omitempty" json:"BeaconID,omitempty"`
 }
 
 // DetectedInterface is an interface being reported by a tracker
 type DetectedInterface struct {
 	Interface
 	Data map[string]string
+
+	// Departed tells that the interface is known to have left (e.g. it got
+	// disconnected from the network), rather than having been seen.
+	Departed bool
+
+	// LastSeenAt is when the interface was last seen, when known by the
+	// tracker (e.g. reported by a network controller); defaults to now.
+	LastSeenAt time.Time
+
+	// DeviceID identifies the device the sighting is about, when known by the
+	// tracker (e.g. an external presence source); the interface is then not
+	// used for looking up the device, and no new device is discovered.
+	DeviceID string
 }
 
 const (
@@ -49,6 +72,12 @@ var stringToInterfaceType = map[string]InterfaceType{
 	"bluetooth": InterfaceBluetooth,
 }
 
+// InterfaceTypeOf returns the interface type given its name, if valid.
+func InterfaceTypeOf(s string) (InterfaceType, bool) {
+	t, ok := stringToInterfaceType[s]
+	return t, ok
+}
+
 func (i InterfaceType) String() string {
 	return interfaceTypeToString[i]
 }